@@ -9,14 +9,30 @@ import (
 )
 
 func main() {
+	store, err := openStore()
+	if err != nil {
+		log.Fatalf("Ошибка инициализации БД: %v", err)
+	}
+	defer store.Close()
+
+	version, err := store.SchemaVersion()
+	if err != nil {
+		log.Fatalf("Ошибка чтения версии схемы БД: %v", err)
+	}
+	log.Printf("Версия схемы БД: %d", version)
+
+	server.Run(store)
+}
+
+// openStore подключается к PostgreSQL, если задана TODO_PGDSN, иначе открывает файл SQLite из TODO_DBFILE
+func openStore() (*db.SQLStore, error) {
+	if dsn := os.Getenv("TODO_PGDSN"); dsn != "" {
+		return db.OpenPostgres(dsn)
+	}
+
 	dbFile := os.Getenv("TODO_DBFILE")
 	if dbFile == "" {
 		dbFile = "scheduler.db"
 	}
-	if err := db.Init(dbFile); err != nil {
-		log.Fatalf("Ошибка инициализации БД: %v", err)
-	}
-	defer db.Close()
-
-	server.Run()
+	return db.OpenSQLite(dbFile)
 }
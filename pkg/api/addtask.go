@@ -2,6 +2,8 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,31 +12,228 @@ import (
 	"github.com/Evrard-ro/final_project/pkg/db"
 )
 
-func addTaskHandler(w http.ResponseWriter, r *http.Request) {
-	var task db.Task
+// taskError — ошибка операции с задачей вместе с кодом ответа HTTP
+type taskError struct {
+	status int
+	msg    string
+}
 
-	// Десериализуем JSON
-	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+func (e *taskError) Error() string {
+	return e.msg
+}
+
+func badRequest(msg string) error {
+	return &taskError{status: http.StatusBadRequest, msg: msg}
+}
+
+func notFound(msg string) error {
+	return &taskError{status: http.StatusNotFound, msg: msg}
+}
+
+// writeTaskError отвечает ошибкой операции с задачей; прочие ошибки считаются ошибками хранилища
+func writeTaskError(w http.ResponseWriter, err error) {
+	var te *taskError
+	if errors.As(err, &te) {
+		writeError(w, te.msg, te.status)
 		return
 	}
+	writeError(w, err.Error(), http.StatusInternalServerError)
+}
 
+// checkTask проверяет задачу перед добавлением или изменением;
+// now задаёт «сегодня» в часовом поясе запроса
+func (a *API) checkTask(task *db.Task, now time.Time) error {
 	// Проверяем заголовок
 	if strings.TrimSpace(task.Title) == "" {
-		writeError(w, "Не указан заголовок задачи", http.StatusBadRequest)
-		return
+		return badRequest("Не указан заголовок задачи")
+	}
+
+	// Проверяем теги
+	var err error
+	if task.Tags, err = normalizeTags(task.Tags); err != nil {
+		return badRequest(err.Error())
+	}
+
+	// Проверяем приоритет
+	if err := checkPriority(task); err != nil {
+		return badRequest(err.Error())
+	}
+
+	// Проверяем проект
+	if err := a.checkTaskProject(task); err != nil {
+		return badRequest(err.Error())
+	}
+
+	// Проверяем зависимости
+	if err := a.checkTaskDeps(task); err != nil {
+		return badRequest(err.Error())
 	}
 
 	// Проверяем дату
-	if err := checkDate(&task); err != nil {
+	if err := a.checkDate(task, now); err != nil {
+		return badRequest(err.Error())
+	}
+	return nil
+}
+
+// addTask проверяет новую задачу вместе с чек-листом и добавляет её
+func (a *API) addTask(task *db.Task, now time.Time) (int64, error) {
+	// Число оставшихся повторений задаётся правилом; checkDate вычтет пропущенные
+	task.Remaining = repeatCount(task.Repeat)
+
+	if err := a.checkTask(task, now); err != nil {
+		return 0, err
+	}
+	if err := checkTaskItems(task); err != nil {
+		return 0, badRequest(err.Error())
+	}
+
+	return a.store.AddTask(task)
+}
+
+// updateTask проверяет и изменяет задачу. Чек-лист не меняется: для него есть /api/task/items.
+func (a *API) updateTask(task *db.Task, now time.Time) error {
+	if task.ID == "" {
+		return badRequest("Не указан идентификатор")
+	}
+
+	// Счётчик повторений сохраняется, а при смене правила начинается заново
+	stored, err := a.store.GetTask(task.ID)
+	if err != nil {
+		return notFound("Задача не найдена")
+	}
+	if stored.Repeat == task.Repeat {
+		task.Remaining = stored.Remaining
+	} else {
+		task.Remaining = repeatCount(task.Repeat)
+	}
+	task.Items = nil
+
+	if err := a.checkTask(task, now); err != nil {
+		return err
+	}
+
+	err = a.store.UpdateTask(task)
+	if errors.Is(err, db.ErrDependencyCycle) {
+		return badRequest("Зависимости задач образуют цикл")
+	}
+	if err != nil {
+		return notFound(err.Error())
+	}
+	return nil
+}
+
+// deleteTask перемещает задачу в корзину и возвращает её прежнее состояние для отмены
+func (a *API) deleteTask(id string) (*db.Task, error) {
+	if id == "" {
+		return nil, badRequest("Не указан идентификатор")
+	}
+
+	task, err := a.store.GetTask(id)
+	if err != nil {
+		return nil, notFound("Задача не найдена")
+	}
+
+	if err := a.store.DeleteTask(id); err != nil {
+		return nil, notFound(err.Error())
+	}
+	return task, nil
+}
+
+// completeTask отмечает выполнение задачи: повторяющаяся задача переносится на следующую дату
+// со сброшенным чек-листом, остальные завершаются. Задачу с невыполненными блокерами можно
+// выполнить только с force. Возвращает состояние задачи до выполнения и запись в истории для отмены.
+func (a *API) completeTask(id string, now time.Time, force bool) (*db.Task, int64, error) {
+	if id == "" {
+		return nil, 0, badRequest("Не указан идентификатор")
+	}
+
+	// Получаем задачу
+	task, err := a.store.GetTask(id)
+	if err != nil {
+		return nil, 0, notFound("Задача не найдена")
+	}
+	if task.Done {
+		return nil, 0, badRequest("Задача уже выполнена")
+	}
+	if len(task.BlockedBy) > 0 && !force {
+		return nil, 0, &taskError{
+			status: http.StatusConflict,
+			msg:    "Задача заблокирована невыполненными задачами: " + strings.Join(task.BlockedBy, ", "),
+		}
+	}
+
+	// Если правило повторения отсутствует или это последний разрешённый повтор - завершаем задачу
+	if task.Repeat == "" || task.Remaining == 1 {
+		completionID, err := a.finishTask(task, now)
+		return task, completionID, err
+	}
+
+	// Парсим дату задачи
+	taskDate, err := time.Parse(DateFormat, task.Date)
+	if err != nil {
+		return nil, 0, badRequest(err.Error())
+	}
+
+	// Вычисляем следующую дату от даты задачи
+	next, err := a.nextDate(taskDate, task.Date, task.Repeat)
+	if errors.Is(err, ErrRecurrenceEnded) {
+		// Дата окончания правила пройдена - задача завершена
+		completionID, err := a.finishTask(task, now)
+		return task, completionID, err
+	}
+	if err != nil {
+		return nil, 0, badRequest(err.Error())
+	}
+
+	remaining := task.Remaining
+	if remaining > 1 {
+		remaining--
+	}
+
+	// Новая дата, сброс чек-листа и запись в истории сохраняются вместе
+	var completionID int64
+	err = a.store.InTx(func(tx db.TaskStore) error {
+		// Обновляем дату задачи
+		if err := tx.UpdateTaskDate(id, next, remaining); err != nil {
+			return err
+		}
+
+		// Для следующего повторения чек-лист начинается заново
+		if err := tx.ResetTaskItems(id); err != nil {
+			return err
+		}
+
+		var err error
+		completionID, err = (&API{store: tx}).recordCompletion(task, now)
+		return err
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return task, completionID, nil
+}
+
+func (a *API) addTaskHandler(w http.ResponseWriter, r *http.Request) {
+	var task db.Task
+
+	// Десериализуем JSON
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Дата проверяется относительно «сегодня» в часовом поясе запроса
+	now, err := requestNow(r)
+	if err != nil {
 		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Добавляем задачу в БД
-	id, err := db.AddTask(&task)
+	id, err := a.addTask(&task, now)
 	if err != nil {
-		writeError(w, err.Error(), http.StatusInternalServerError)
+		writeTaskError(w, err)
 		return
 	}
 
@@ -42,14 +241,14 @@ func addTaskHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]string{"id": strconv.FormatInt(id, 10)}, http.StatusOK)
 }
 
-func getTaskHandler(w http.ResponseWriter, r *http.Request) {
+func (a *API) getTaskHandler(w http.ResponseWriter, r *http.Request) {
 	id := r.FormValue("id")
 	if id == "" {
 		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
 		return
 	}
 
-	task, err := db.GetTask(id)
+	task, err := a.store.GetTask(id)
 	if err != nil {
 		writeError(w, "Задача не найдена", http.StatusNotFound)
 		return
@@ -58,7 +257,7 @@ func getTaskHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, task, http.StatusOK)
 }
 
-func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
+func (a *API) updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	var task db.Task
 
 	// Десериализуем JSON
@@ -67,27 +266,16 @@ func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Проверяем ID
-	if task.ID == "" {
-		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
-		return
-	}
-
-	// Проверяем заголовок
-	if strings.TrimSpace(task.Title) == "" {
-		writeError(w, "Не указан заголовок задачи", http.StatusBadRequest)
-		return
-	}
-
-	// Проверяем дату
-	if err := checkDate(&task); err != nil {
+	// Дата проверяется относительно «сегодня» в часовом поясе запроса
+	now, err := requestNow(r)
+	if err != nil {
 		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	// Обновляем задачу в БД
-	if err := db.UpdateTask(&task); err != nil {
-		writeError(w, err.Error(), http.StatusNotFound)
+	if err := a.updateTask(&task, now); err != nil {
+		writeTaskError(w, err)
 		return
 	}
 
@@ -95,61 +283,39 @@ func updateTaskHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]string{}, http.StatusOK)
 }
 
-func deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.FormValue("id")
-	if id == "" {
-		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
+func (a *API) deleteTaskHandler(w http.ResponseWriter, r *http.Request) {
+	// Запоминаем задачу, чтобы удаление можно было отменить
+	task, err := a.deleteTask(r.FormValue("id"))
+	if err != nil {
+		writeTaskError(w, err)
 		return
 	}
 
-	if err := db.DeleteTask(id); err != nil {
-		writeError(w, err.Error(), http.StatusNotFound)
+	if err := a.setUndoToken(w, task, 0); err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	writeJSON(w, map[string]string{}, http.StatusOK)
 }
 
-func taskDoneHandler(w http.ResponseWriter, r *http.Request) {
-	id := r.FormValue("id")
-	if id == "" {
-		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
-		return
-	}
-
-	// Получаем задачу
-	task, err := db.GetTask(id)
-	if err != nil {
-		writeError(w, "Задача не найдена", http.StatusNotFound)
-		return
-	}
-
-	// Если правило повторения отсутствует - удаляем задачу
-	if task.Repeat == "" {
-		if err := db.DeleteTask(id); err != nil {
-			writeError(w, err.Error(), http.StatusInternalServerError)
-			return
-		}
-		writeJSON(w, map[string]string{}, http.StatusOK)
-		return
-	}
-
-	// Парсим дату задачи
-	taskDate, err := time.Parse(DateFormat, task.Date)
+// taskDoneHandler обрабатывает POST /api/task/done?id=&force=.
+// force=true выполняет задачу, даже если её блокеры ещё не выполнены.
+func (a *API) taskDoneHandler(w http.ResponseWriter, r *http.Request) {
+	now, err := requestNow(r)
 	if err != nil {
 		writeError(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Вычисляем следующую дату от даты задачи
-	next, err := NextDate(taskDate, task.Date, task.Repeat)
+	task, completionID, err := a.completeTask(r.FormValue("id"), now, r.FormValue("force") == "true")
 	if err != nil {
-		writeError(w, err.Error(), http.StatusBadRequest)
+		writeTaskError(w, err)
 		return
 	}
 
-	// Обновляем дату задачи
-	if err := db.UpdateTaskDate(id, next); err != nil {
+	// Отмена вернёт задаче прежнюю дату и отметки чек-листа
+	if err := a.setUndoToken(w, task, completionID); err != nil {
 		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -157,8 +323,64 @@ func taskDoneHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, map[string]string{}, http.StatusOK)
 }
 
-func checkDate(task *db.Task) error {
-	now := time.Now()
+// finishTask завершает задачу, у которой больше не будет повторений: удаляет её окончательно
+// или, если включено TODO_KEEP_DONE, оставляет с отметкой done. Выполнение попадает в историю.
+// Возвращает идентификатор записи в истории.
+func (a *API) finishTask(task *db.Task, now time.Time) (int64, error) {
+	var completionID int64
+	err := a.store.InTx(func(tx db.TaskStore) error {
+		var err error
+		if keepDoneTasks {
+			err = tx.MarkTaskDone(task.ID)
+		} else {
+			// Выполненная задача не попадает в корзину: запись о ней остаётся в истории
+			err = tx.PurgeTask(task.ID)
+		}
+		if err != nil {
+			return err
+		}
+		completionID, err = (&API{store: tx}).recordCompletion(task, now)
+		return err
+	})
+	return completionID, err
+}
+
+const (
+	TimeFormat = "15:04"
+	// MaxTaskDuration — наибольшая продолжительность задачи в минутах
+	MaxTaskDuration = 24 * 60
+)
+
+// checkTime проверяет необязательные время (ЧЧ:ММ) и продолжительность задачи в минутах
+func checkTime(task *db.Task) error {
+	if task.Time != "" {
+		if _, err := time.Parse(TimeFormat, task.Time); err != nil || len(task.Time) != len(TimeFormat) {
+			return errors.New("Время задачи должно быть в формате ЧЧ:ММ")
+		}
+	}
+	if task.Duration < 0 || task.Duration > MaxTaskDuration {
+		return fmt.Errorf("Продолжительность задачи должна быть от 0 до %d минут", MaxTaskDuration)
+	}
+	if task.Duration > 0 && task.Time == "" {
+		return errors.New("Для продолжительности нужно указать время задачи")
+	}
+	return nil
+}
+
+// checkPriority проверяет приоритет задачи
+func checkPriority(task *db.Task) error {
+	if task.Priority < 0 || task.Priority > db.MaxPriority {
+		return fmt.Errorf("Приоритет задачи должен быть от 0 до %d", db.MaxPriority)
+	}
+	return nil
+}
+
+// checkDate проверяет дату задачи; now задаёт «сегодня» в нужном часовом поясе
+func (a *API) checkDate(task *db.Task, now time.Time) error {
+	// Проверяем время и продолжительность
+	if err := checkTime(task); err != nil {
+		return err
+	}
 
 	// Если дата не указана, берём сегодняшнюю
 	if task.Date == "" {
@@ -171,10 +393,25 @@ func checkDate(task *db.Task) error {
 		return err
 	}
 
+	// Повторения с ограниченным числом отсчитываются от исходной даты задачи
+	if len(task.Repeat) > 0 && task.Remaining > 0 && afterNow(now, t) {
+		if err := a.skipRepeats(task, now); err != nil {
+			return err
+		}
+		t, err = time.Parse(DateFormat, task.Date)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Если есть правило повторения, проверяем его
 	var next string
 	if len(task.Repeat) > 0 {
-		next, err = NextDate(now, task.Date, task.Repeat)
+		next, err = a.nextDate(now, task.Date, task.Repeat)
+		// Правило без следующих дат допустимо, пока не прошла дата самой задачи
+		if errors.Is(err, ErrRecurrenceEnded) && !afterNow(now, t) {
+			err = nil
+		}
 		if err != nil {
 			return err
 		}
@@ -191,6 +428,39 @@ func checkDate(task *db.Task) error {
 		}
 	}
 
+	// Дата задачи не может быть позже окончания повторений
+	if _, end, err := splitRepeatEnd(task.Repeat); err == nil && end.until != "" && task.Date > end.until {
+		return errors.New("Дата задачи позже даты окончания повторений")
+	}
+
+	return nil
+}
+
+// skipRepeats переносит прошедшую дату задачи на первое повторение после now и вычитает
+// пропущенные повторения из task.Remaining. Если последнее разрешённое повторение
+// приходится на сегодня, задача остаётся на сегодня.
+func (a *API) skipRepeats(task *db.Task, now time.Time) error {
+	today := now.Format(DateFormat)
+	date, remaining := task.Date, task.Remaining
+	for date <= today && remaining > 1 {
+		t, err := time.Parse(DateFormat, date)
+		if err != nil {
+			return err
+		}
+		next, err := a.nextDate(t, date, task.Repeat)
+		if errors.Is(err, ErrRecurrenceEnded) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		date, remaining = next, remaining-1
+	}
+	if date < today {
+		return ErrRecurrenceEnded
+	}
+
+	task.Date, task.Remaining = date, remaining
 	return nil
 }
 
@@ -1,28 +1,75 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+	"time"
 
-func Init() {
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
 
+// Init читает настройки API из переменных окружения
+func Init() error {
 	InitAuth()
+	InitCompletions()
+	if err := InitUndo(); err != nil {
+		return err
+	}
+	if err := InitTrash(); err != nil {
+		return err
+	}
+	return InitTimezone()
+}
+
+// API — обработчики HTTP, работающие с хранилищем задач
+type API struct {
+	store db.TaskStore
+	undo  undoStore
+}
+
+// New создаёт обработчики поверх хранилища store
+func New(store db.TaskStore) *API {
+	return &API{
+		store: store,
+		undo:  undoStore{entries: make(map[string]*undoEntry)},
+	}
+}
+
+// Register регистрирует обработчики API в mux
+func (a *API) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/api/nextdate", a.nextDayHandler)
+	mux.HandleFunc("/api/nextdates", a.nextDatesHandler)
+	mux.HandleFunc("/api/task", auth(a.taskHandler))
+	mux.HandleFunc("/api/tasks", auth(a.tasksHandler))
+	mux.HandleFunc("/api/tasks/bulk", auth(a.bulkHandler))
+	mux.HandleFunc("/api/task/done", auth(a.taskDoneHandler))
+	mux.HandleFunc("/api/task/items", auth(a.taskItemsHandler))
+	mux.HandleFunc("/api/holidays", auth(a.holidaysHandler))
+	mux.HandleFunc("/api/completions", auth(a.completionsHandler))
+	mux.HandleFunc("/api/undo", auth(a.undoHandler))
+	mux.HandleFunc("/api/trash", auth(a.trashHandler))
+	mux.HandleFunc("/api/trash/restore", auth(a.restoreTrashHandler))
+	mux.HandleFunc("/api/projects", auth(a.projectsHandler))
+	mux.HandleFunc("/api/tags", auth(a.tagsHandler))
+	mux.HandleFunc("/api/tags/merge", auth(a.mergeTagsHandler))
+	mux.HandleFunc("/api/schema", auth(a.schemaHandler))
+	mux.HandleFunc("/api/signin", signInHandler)
+}
 
-	http.HandleFunc("/api/nextdate", nextDayHandler)
-	http.HandleFunc("/api/task", auth(taskHandler))
-	http.HandleFunc("/api/tasks", auth(tasksHandler))
-	http.HandleFunc("/api/task/done", auth(taskDoneHandler))
-	http.HandleFunc("/api/signin", signInHandler)
+// nextDate вычисляет следующую дату с учётом праздников из хранилища
+func (a *API) nextDate(now time.Time, dstart string, repeat string) (string, error) {
+	return nextDate(now, dstart, repeat, a.store.HolidaySet)
 }
 
-func taskHandler(w http.ResponseWriter, r *http.Request) {
+func (a *API) taskHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
-		addTaskHandler(w, r)
+		a.addTaskHandler(w, r)
 	case http.MethodGet:
-		getTaskHandler(w, r)
+		a.getTaskHandler(w, r)
 	case http.MethodPut:
-		updateTaskHandler(w, r)
+		a.updateTaskHandler(w, r)
 	case http.MethodDelete:
-		deleteTaskHandler(w, r)
+		a.deleteTaskHandler(w, r)
 	default:
 		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
 	}
@@ -0,0 +1,486 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+// newTestServer поднимает обработчики поверх хранилища в памяти
+func newTestServer(t *testing.T) (*httptest.Server, *db.MemoryStore) {
+	store := db.NewMemoryStore()
+	mux := http.NewServeMux()
+	New(store).Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+func request(t *testing.T, srv *httptest.Server, method, path string, body any) (*http.Response, map[string]any) {
+	var reader *strings.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		assert.NoError(t, err)
+		reader = strings.NewReader(string(data))
+	} else {
+		reader = strings.NewReader("")
+	}
+
+	req, err := http.NewRequest(method, srv.URL+path, reader)
+	assert.NoError(t, err)
+	resp, err := srv.Client().Do(req)
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	var m map[string]any
+	json.NewDecoder(resp.Body).Decode(&m)
+	return resp, m
+}
+
+func TestTaskLifecycle(t *testing.T) {
+	srv, store := newTestServer(t)
+	today := time.Now().Format(DateFormat)
+
+	resp, m := request(t, srv, http.MethodPost, "/api/task", map[string]any{"title": "Тест", "repeat": "d 1"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	id, _ := m["id"].(string)
+	assert.NotEmpty(t, id)
+
+	_, m = request(t, srv, http.MethodGet, "/api/task?id="+id, nil)
+	assert.Equal(t, today, m["date"])
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	task, err := store.GetTask(id)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Now().AddDate(0, 0, 1).Format(DateFormat), task.Date)
+
+	resp, _ = request(t, srv, http.MethodDelete, "/api/task?id="+id, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	token := resp.Header.Get(UndoTokenHeader)
+	assert.NotEmpty(t, token)
+	_, err = store.GetTask(id)
+	assert.Error(t, err)
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/undo?token="+url.QueryEscape(token), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = store.GetTask(id)
+	assert.NoError(t, err)
+
+	resp, m = request(t, srv, http.MethodGet, "/api/task?id=missing", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	assert.NotEmpty(t, m["error"])
+}
+
+func TestTasksSearch(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	for _, task := range []*db.Task{
+		{Date: "20300101", Title: "Купить молоко"},
+		{Date: "20300102", Title: "Позвонить", Comment: "Про Молоко"},
+		{Date: "20300103", Title: "Отдохнуть"},
+	} {
+		_, err := store.AddTask(task)
+		assert.NoError(t, err)
+	}
+
+	_, m := request(t, srv, http.MethodGet, "/api/tasks", nil)
+	assert.Len(t, m["tasks"], 3)
+	_, m = request(t, srv, http.MethodGet, "/api/tasks?search="+url.QueryEscape("Молоко"), nil)
+	assert.Len(t, m["tasks"], 2)
+	_, m = request(t, srv, http.MethodGet, "/api/tasks?search="+url.QueryEscape("молоко NOT позвон*"), nil)
+	if assert.Len(t, m["tasks"], 1) {
+		assert.Equal(t, "Купить <mark>молоко</mark>", m["tasks"].([]any)[0].(map[string]any)["snippet"])
+	}
+	resp, m := request(t, srv, http.MethodGet, "/api/tasks?search="+url.QueryEscape("NOT молоко"), nil)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.NotEmpty(t, m["error"])
+	_, m = request(t, srv, http.MethodGet, "/api/tasks?search=02.01.2030", nil)
+	assert.Len(t, m["tasks"], 1)
+}
+
+// Праздники из хранилища влияют на правила с рабочими днями только своего экземпляра
+func TestHolidaysPerStore(t *testing.T) {
+	srv, store := newTestServer(t)
+	other, _ := newTestServer(t)
+
+	assert.NoError(t, store.AddHoliday(&db.Holiday{Date: "20240129"}))
+
+	path := "/api/nextdate?now=20240126&date=20240126&repeat=bd+1"
+	for _, v := range []struct {
+		srv  *httptest.Server
+		want string
+	}{
+		{srv, "20240130"},
+		{other, "20240129"},
+	} {
+		resp, err := v.srv.Client().Get(v.srv.URL + path)
+		assert.NoError(t, err)
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, string(body))
+	}
+}
+
+// Чек-лист повторяющейся задачи сбрасывается при переносе на следующую дату, а отмена возвращает отметки
+// Пропущенные повторения прошедшей даты вычитаются одинаково для xN и COUNT
+func TestAddTaskRemaining(t *testing.T) {
+	srv, store := newTestServer(t)
+	now := time.Now()
+	day := func(n int) string { return now.AddDate(0, 0, n).Format(DateFormat) }
+
+	tbl := []struct {
+		date      string
+		repeat    string
+		want      string
+		remaining int
+	}{
+		{day(-1), "d 1 x2", day(0), 1},
+		{day(-1), "RRULE:FREQ=DAILY;COUNT=2", day(0), 1},
+		{day(-2), "d 1 x5", day(1), 2},
+		{day(-2), "RRULE:FREQ=DAILY;COUNT=5", day(1), 2},
+		{day(-4), "d 2 x4", day(2), 1},
+		{day(-4), "RRULE:FREQ=DAILY;INTERVAL=2;COUNT=4", day(2), 1},
+		{day(1), "d 1 x2", day(1), 2},
+		{day(1), "RRULE:FREQ=DAILY;COUNT=2", day(1), 2},
+		{day(-1), "d 1 x1", "", 0},
+		{day(-1), "RRULE:FREQ=DAILY;COUNT=1", "", 0},
+		{day(-3), "d 1 x2", "", 0},
+		{day(-3), "RRULE:FREQ=DAILY;COUNT=2", "", 0},
+	}
+	for _, v := range tbl {
+		resp, m := request(t, srv, http.MethodPost, "/api/task", map[string]any{
+			"date": v.date, "title": "Повтор", "repeat": v.repeat,
+		})
+		if v.want == "" {
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "%s %s", v.date, v.repeat)
+			continue
+		}
+		if !assert.Equal(t, http.StatusOK, resp.StatusCode, "%s %s: %v", v.date, v.repeat, m["error"]) {
+			continue
+		}
+		task, err := store.GetTask(m["id"].(string))
+		assert.NoError(t, err)
+		assert.Equal(t, v.want, task.Date, v.repeat)
+		assert.Equal(t, v.remaining, task.Remaining, v.repeat)
+	}
+}
+
+func TestTaskItemsReset(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	resp, m := request(t, srv, http.MethodPost, "/api/task", map[string]any{
+		"title": "Зарядка", "repeat": "d 1",
+		"items": []map[string]any{{"title": "Разминка"}, {"title": "Бег"}},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	id, _ := m["id"].(string)
+
+	items, err := store.TaskItems(id)
+	assert.NoError(t, err)
+	if !assert.Len(t, items, 2) {
+		return
+	}
+	resp, _ = request(t, srv, http.MethodPut, "/api/task/items", map[string]any{"id": items[0].ID, "title": "Разминка", "done": true})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, m = request(t, srv, http.MethodGet, "/api/tasks", nil)
+	assert.Equal(t, map[string]any{"done": 1.0, "total": 2.0}, m["tasks"].([]any)[0].(map[string]any)["progress"])
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	task, err := store.GetTask(id)
+	assert.NoError(t, err)
+	assert.Equal(t, &db.Progress{Done: 0, Total: 2}, task.Progress)
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/undo?token="+url.QueryEscape(resp.Header.Get(UndoTokenHeader)), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	task, err = store.GetTask(id)
+	assert.NoError(t, err)
+	assert.Equal(t, &db.Progress{Done: 1, Total: 2}, task.Progress)
+}
+
+// Чек-лист задачи в корзине нельзя ни прочитать, ни изменить
+func TestTaskItemsTrashed(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	resp, m := request(t, srv, http.MethodPost, "/api/task", map[string]any{
+		"title": "Покупки", "items": []map[string]any{{"title": "Хлеб"}, {"title": "Сыр"}},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	id, _ := m["id"].(string)
+	items, err := store.TaskItems(id)
+	assert.NoError(t, err)
+	if !assert.Len(t, items, 2) {
+		return
+	}
+	assert.NoError(t, store.DeleteTask(id))
+
+	resp, _ = request(t, srv, http.MethodGet, "/api/task/items?task_id="+id, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/items", map[string]any{"task_id": id, "title": "Молоко"})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = request(t, srv, http.MethodPut, "/api/task/items", map[string]any{"id": items[0].ID, "title": "Хлеб", "done": true, "position": 2})
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp, _ = request(t, srv, http.MethodDelete, "/api/task/items?id="+items[1].ID, nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	after, err := store.TaskItems(id)
+	assert.NoError(t, err)
+	assert.Equal(t, items, after)
+}
+
+// failingStore — хранилище, в котором не удаётся записать выполнение в историю
+type failingStore struct {
+	db.TaskStore
+}
+
+func (s failingStore) AddCompletion(*db.Completion) (int64, error) {
+	return 0, errors.New("completion log is unavailable")
+}
+
+func (s failingStore) InTx(fn func(tx db.TaskStore) error) error {
+	return s.TaskStore.InTx(func(tx db.TaskStore) error {
+		return fn(failingStore{tx})
+	})
+}
+
+// Если выполнение не удалось записать в историю, задача не меняется
+func TestTaskDoneRollback(t *testing.T) {
+	store := db.NewMemoryStore()
+	mux := http.NewServeMux()
+	New(failingStore{store}).Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	today := time.Now().Format(DateFormat)
+
+	resp, m := request(t, srv, http.MethodPost, "/api/task", map[string]any{
+		"title": "Зарядка", "repeat": "d 1",
+		"items": []map[string]any{{"title": "Разминка", "done": true}},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	id, _ := m["id"].(string)
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	task, err := store.GetTask(id)
+	assert.NoError(t, err)
+	assert.Equal(t, today, task.Date)
+	assert.Equal(t, &db.Progress{Done: 1, Total: 1}, task.Progress)
+
+	// Задача без повторения тоже остаётся на месте
+	resp, m = request(t, srv, http.MethodPost, "/api/task", map[string]any{"title": "Разовая"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	id, _ = m["id"].(string)
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	task, err = store.GetTask(id)
+	assert.NoError(t, err)
+	assert.False(t, task.Done)
+}
+
+// flakyStore — хранилище, в котором первые fails удалений записи истории не удаются
+type flakyStore struct {
+	db.TaskStore
+	fails *int
+}
+
+func (s flakyStore) DeleteCompletion(id int64) error {
+	if *s.fails > 0 {
+		*s.fails--
+		return errors.New("completion log is unavailable")
+	}
+	return s.TaskStore.DeleteCompletion(id)
+}
+
+func (s flakyStore) InTx(fn func(tx db.TaskStore) error) error {
+	return s.TaskStore.InTx(func(tx db.TaskStore) error {
+		return fn(flakyStore{tx, s.fails})
+	})
+}
+
+// Неудачная отмена не восстанавливает задачу наполовину, и её можно повторить тем же токеном
+func TestUndoRollback(t *testing.T) {
+	store := db.NewMemoryStore()
+	fails := 1
+	mux := http.NewServeMux()
+	New(flakyStore{store, &fails}).Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	resp, m := request(t, srv, http.MethodPost, "/api/task", map[string]any{"title": "Разовая"})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	id, _ := m["id"].(string)
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	token := url.QueryEscape(resp.Header.Get(UndoTokenHeader))
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/undo?token="+token, nil)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	_, err := store.GetTask(id)
+	assert.Error(t, err)
+	completions, err := store.Completions(db.CompletionsOptions{Limit: 10, TaskID: id})
+	assert.NoError(t, err)
+	assert.Len(t, completions.Completions, 1)
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/undo?token="+token, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	_, err = store.GetTask(id)
+	assert.NoError(t, err)
+	completions, err = store.Completions(db.CompletionsOptions{Limit: 10, TaskID: id})
+	assert.NoError(t, err)
+	assert.Empty(t, completions.Completions)
+}
+
+// Задачу с невыполненным блокером нельзя завершить без force=true
+func TestTaskDoneBlocked(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	blocker, err := store.AddTask(&db.Task{Date: "20300101", Title: "Купить краску"})
+	assert.NoError(t, err)
+	blockerID := strconv.FormatInt(blocker, 10)
+	resp, m := request(t, srv, http.MethodPost, "/api/task", map[string]any{
+		"date": "20300102", "title": "Покрасить забор", "depends_on": []string{blockerID},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	id, _ := m["id"].(string)
+
+	resp, m = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	assert.NotEmpty(t, m["error"])
+
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+blockerID, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// Цикл зависимостей отклоняется
+	first, err := store.AddTask(&db.Task{Date: "20300101", Title: "Первая"})
+	assert.NoError(t, err)
+	resp, m = request(t, srv, http.MethodPost, "/api/task", map[string]any{
+		"date": "20300101", "title": "Вторая", "depends_on": []string{strconv.FormatInt(first, 10)},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp, m = request(t, srv, http.MethodPut, "/api/task", map[string]any{
+		"id": strconv.FormatInt(first, 10), "date": "20300101", "title": "Первая", "depends_on": []string{m["id"].(string)},
+	})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.NotEmpty(t, m["error"])
+
+	// Повторяющийся блокер снимается выполнением, записанным после появления связи
+	water, err := store.AddTask(&db.Task{Date: "20300101", Title: "Полить цветы", Repeat: "d 1"})
+	assert.NoError(t, err)
+	waterID := strconv.FormatInt(water, 10)
+	resp, m = request(t, srv, http.MethodPost, "/api/task", map[string]any{
+		"date": "20300102", "title": "Пересадить цветы", "depends_on": []string{waterID},
+	})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	id, _ = m["id"].(string)
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+waterID, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	task, err := store.GetTask(id)
+	assert.NoError(t, err)
+	assert.Empty(t, task.BlockedBy)
+	resp, _ = request(t, srv, http.MethodPost, "/api/task/done?id="+id, nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+// Атомарный массовый запрос при ошибке не меняет ничего, обычный — пропускает только ошибочную операцию
+func TestBulk(t *testing.T) {
+	srv, store := newTestServer(t)
+
+	id, err := store.AddTask(&db.Task{Date: "20300101", Title: "Старая"})
+	assert.NoError(t, err)
+	taskID := strconv.FormatInt(id, 10)
+	operations := []map[string]any{
+		{"op": "add", "task": map[string]any{"date": "20300102", "title": "Новая"}},
+		{"op": "move", "id": taskID, "date": "20300105"},
+		{"op": "add", "task": map[string]any{"date": "20300102"}},
+	}
+
+	resp, m := request(t, srv, http.MethodPost, "/api/tasks/bulk", map[string]any{"atomic": true, "operations": operations})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, false, m["applied"])
+	assert.NotEmpty(t, m["error"])
+	page, err := store.Tasks(db.TasksOptions{Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Tasks, 1) {
+		assert.Equal(t, "20300101", page.Tasks[0].Date)
+	}
+
+	resp, m = request(t, srv, http.MethodPost, "/api/tasks/bulk", map[string]any{"operations": operations})
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, true, m["applied"])
+	results, _ := m["results"].([]any)
+	if assert.Len(t, results, 3) {
+		assert.NotEmpty(t, results[0].(map[string]any)["id"])
+		assert.Nil(t, results[1].(map[string]any)["error"])
+		assert.NotEmpty(t, results[2].(map[string]any)["error"])
+	}
+	task, err := store.GetTask(taskID)
+	assert.NoError(t, err)
+	assert.Equal(t, "20300105", task.Date)
+	page, err = store.Tasks(db.TasksOptions{Limit: 10})
+	assert.NoError(t, err)
+	assert.Len(t, page.Tasks, 2)
+}
+
+// Одиночное добавление во время большого массового запроса ждёт блокировку SQLite, а не падает
+func TestBulkConcurrentSQLite(t *testing.T) {
+	store, err := db.OpenSQLite(filepath.Join(t.TempDir(), "scheduler.db"))
+	if !assert.NoError(t, err) {
+		return
+	}
+	t.Cleanup(func() { store.Close() })
+	mux := http.NewServeMux()
+	New(store).Register(mux)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	operations := make([]map[string]any, 0, MaxBulkOperations)
+	for i := 0; i < MaxBulkOperations; i++ {
+		operations = append(operations, map[string]any{"op": "add", "task": map[string]any{
+			"date": "20300101", "title": "Массовая " + strconv.Itoa(i), "repeat": "d 1 x3",
+			"items": []map[string]any{{"title": "Первый"}, {"title": "Второй"}},
+		}})
+	}
+
+	const rounds = 5
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds; i++ {
+			resp, m := request(t, srv, http.MethodPost, "/api/tasks/bulk", map[string]any{"operations": operations})
+			assert.Equal(t, http.StatusOK, resp.StatusCode, m["error"])
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < rounds*10; i++ {
+			resp, m := request(t, srv, http.MethodPost, "/api/task", map[string]any{"date": "20300101", "title": "Одиночная"})
+			assert.Equal(t, http.StatusOK, resp.StatusCode, m["error"])
+		}
+	}()
+	wg.Wait()
+
+	page, err := store.Tasks(db.TasksOptions{Limit: 1000})
+	assert.NoError(t, err)
+	assert.Equal(t, rounds*MaxBulkOperations+rounds*10, len(page.Tasks))
+}
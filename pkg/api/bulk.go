@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+// MaxBulkOperations — наибольшее число операций в одном запросе /api/tasks/bulk
+const MaxBulkOperations = 100
+
+// BulkOperation — одна операция в POST /api/tasks/bulk.
+// add и update принимают задачу в task, delete, done и move — идентификатор в id.
+type BulkOperation struct {
+	Op   string   `json:"op"`
+	Task *db.Task `json:"task,omitempty"`
+	ID   string   `json:"id,omitempty"`
+	// Date — новая дата задачи для move в формате 20060102
+	Date string `json:"date,omitempty"`
+	// Force — для done: выполнить задачу, даже если её блокеры ещё не выполнены
+	Force bool `json:"force,omitempty"`
+}
+
+// BulkReq — тело POST /api/tasks/bulk
+type BulkReq struct {
+	// Atomic — при ошибке любой операции не применять ни одну
+	Atomic     bool             `json:"atomic"`
+	Operations []*BulkOperation `json:"operations"`
+}
+
+// BulkResult — результат операции: идентификатор задачи или ошибка
+type BulkResult struct {
+	Op    string `json:"op"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type BulkResp struct {
+	// Applied — изменения сохранены; false, если атомарный запрос отменён из-за ошибки
+	Applied bool          `json:"applied"`
+	Results []*BulkResult `json:"results"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// errBulkFailed прерывает транзакцию атомарного запроса после первой ошибки
+var errBulkFailed = errors.New("bulk operation failed")
+
+// bulkHandler обрабатывает POST /api/tasks/bulk. Операции выполняются по порядку в одной транзакции;
+// ошибка операции отменяет только её изменения, а с atomic=true — все изменения запроса.
+// Отменить массовые изменения через /api/undo нельзя.
+func (a *API) bulkHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BulkReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) == 0 {
+		writeError(w, "Не указаны операции", http.StatusBadRequest)
+		return
+	}
+	if len(req.Operations) > MaxBulkOperations {
+		writeError(w, fmt.Sprintf("Слишком много операций: допустимо не больше %d", MaxBulkOperations), http.StatusBadRequest)
+		return
+	}
+
+	// Даты проверяются относительно «сегодня» в часовом поясе запроса
+	now, err := requestNow(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := BulkResp{Results: make([]*BulkResult, 0, len(req.Operations))}
+	err = a.store.InTx(func(tx db.TaskStore) error {
+		for i, op := range req.Operations {
+			result := &BulkResult{}
+			if op != nil {
+				result.Op = op.Op
+			}
+			resp.Results = append(resp.Results, result)
+
+			err := tx.InTx(func(opTx db.TaskStore) error {
+				id, err := (&API{store: opTx}).bulkOperation(op, now)
+				result.ID = id
+				return err
+			})
+			if err == nil {
+				continue
+			}
+
+			var te *taskError
+			if !errors.As(err, &te) {
+				// Ошибка хранилища прерывает весь запрос
+				return err
+			}
+			result.Error = te.msg
+			if req.Atomic {
+				resp.Error = fmt.Sprintf("Операция %d: %s", i+1, te.msg)
+				return errBulkFailed
+			}
+		}
+		return nil
+	})
+	if errors.Is(err, errBulkFailed) {
+		writeJSON(w, resp, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Applied = true
+	writeJSON(w, resp, http.StatusOK)
+}
+
+// bulkOperation выполняет одну операцию массового запроса и возвращает идентификатор задачи
+func (a *API) bulkOperation(op *BulkOperation, now time.Time) (string, error) {
+	if op == nil {
+		return "", badRequest("Пустая операция")
+	}
+
+	switch op.Op {
+	case "add":
+		if op.Task == nil {
+			return "", badRequest("Не указана задача")
+		}
+		id, err := a.addTask(op.Task, now)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(id, 10), nil
+	case "update":
+		if op.Task == nil {
+			return "", badRequest("Не указана задача")
+		}
+		return op.Task.ID, a.updateTask(op.Task, now)
+	case "delete":
+		_, err := a.deleteTask(op.ID)
+		return op.ID, err
+	case "done":
+		_, _, err := a.completeTask(op.ID, now, op.Force)
+		return op.ID, err
+	case "move":
+		return op.ID, a.moveTask(op.ID, op.Date, now)
+	default:
+		return "", badRequest("Неизвестная операция: " + op.Op)
+	}
+}
+
+// moveTask переносит задачу на дату date; дата проверяется так же, как при изменении задачи
+func (a *API) moveTask(id, date string, now time.Time) error {
+	if id == "" {
+		return badRequest("Не указан идентификатор")
+	}
+	if date == "" {
+		return badRequest("Не указана дата")
+	}
+
+	task, err := a.store.GetTask(id)
+	if err != nil {
+		return notFound("Задача не найдена")
+	}
+	task.Date = date
+	if err := a.checkDate(task, now); err != nil {
+		return badRequest(err.Error())
+	}
+
+	if err := a.store.UpdateTaskDate(id, task.Date, task.Remaining); err != nil {
+		return notFound(err.Error())
+	}
+	return nil
+}
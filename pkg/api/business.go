@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// maxBusinessDays — наибольшее число рабочих дней в месяце
+	maxBusinessDays = 23
+	// maxSearchDays ограничивает поиск даты, если праздники закрывают все подходящие дни
+	maxSearchDays = 366 * 100
+)
+
+// holidaySource возвращает множество праздничных дат в формате DateFormat
+type holidaySource func() (map[string]bool, error)
+
+// noHolidays — календарь без праздников
+func noHolidays() (map[string]bool, error) {
+	return map[string]bool{}, nil
+}
+
+// usesBusinessDays возвращает true, если правилу нужен календарь праздников
+func usesBusinessDays(parts []string) bool {
+	switch parts[0] {
+	case "bd":
+		return true
+	case "m":
+		return len(parts) > 1 && strings.Contains(parts[1], "b")
+	}
+	return false
+}
+
+// isBusinessDay возвращает true для будних дней, не отмеченных как праздники
+func isBusinessDay(date time.Time, holidays map[string]bool) bool {
+	if date.Weekday() == time.Saturday || date.Weekday() == time.Sunday {
+		return false
+	}
+	return !holidays[date.Format(DateFormat)]
+}
+
+// addBusinessDays сдвигает дату на n рабочих дней вперёд
+func addBusinessDays(date time.Time, n int, holidays map[string]bool) (time.Time, error) {
+	skipped := 0
+	for n > 0 {
+		date = date.AddDate(0, 0, 1)
+		if isBusinessDay(date, holidays) {
+			n--
+			skipped = 0
+			continue
+		}
+		skipped++
+		if skipped > maxSearchDays {
+			return date, errors.New("no business days found")
+		}
+	}
+	return date, nil
+}
+
+// businessDayPosition возвращает номер рабочего дня в месяце от начала и от конца месяца
+func businessDayPosition(date time.Time, holidays map[string]bool) (first, last int) {
+	lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	for d := 1; d <= lastDay; d++ {
+		day := time.Date(date.Year(), date.Month(), d, 0, 0, 0, 0, date.Location())
+		if !isBusinessDay(day, holidays) {
+			continue
+		}
+		if d <= date.Day() {
+			first++
+		}
+		if d >= date.Day() {
+			last++
+		}
+	}
+	return first, last
+}
+
+// parseMonthDays парсит список дней m правила: обычные дни (15, -1) и рабочие дни (1b, -1b)
+func parseMonthDays(s string) (days []int, businessDays []int, err error) {
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		business := strings.HasSuffix(p, "b")
+		num, err := strconv.Atoi(strings.TrimSuffix(p, "b"))
+		if err != nil {
+			return nil, nil, errors.New("invalid day list in m rule")
+		}
+		if business {
+			businessDays = append(businessDays, num)
+		} else {
+			days = append(days, num)
+		}
+	}
+	return days, businessDays, nil
+}
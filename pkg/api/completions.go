@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+const (
+	DefaultCompletionsLimit = 50
+)
+
+// keepDoneTasks — сохранять выполненные задачи с отметкой done вместо удаления
+var keepDoneTasks bool
+
+type CompletionsResp struct {
+	Completions []*db.Completion `json:"completions"`
+	// NextCursor передаётся в параметре cursor для получения следующей страницы
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// InitCompletions читает настройку TODO_KEEP_DONE
+func InitCompletions() {
+	keepDoneTasks, _ = strconv.ParseBool(os.Getenv("TODO_KEEP_DONE"))
+}
+
+// recordCompletion сохраняет в истории выполнение задачи, назначенной на task.Date
+func (a *API) recordCompletion(task *db.Task, now time.Time) (int64, error) {
+	return a.store.AddCompletion(&db.Completion{
+		TaskID:      task.ID,
+		Title:       task.Title,
+		Date:        task.Date,
+		CompletedAt: now.UTC().Format(time.RFC3339),
+	})
+}
+
+// completionsHandler обрабатывает GET /api/completions?from=&to=&task_id=&limit=&cursor=.
+// Границы from и to включаются и задаются датами в часовом поясе запроса.
+// Следующая страница запрашивается с cursor из next_cursor предыдущего ответа.
+func (a *API) completionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	loc, err := requestLocation(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	opts := db.CompletionsOptions{TaskID: r.FormValue("task_id"), Cursor: r.FormValue("cursor")}
+	if opts.Limit, err = parseLimit(r, DefaultCompletionsLimit); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s := r.FormValue("from"); s != "" {
+		t, err := time.ParseInLocation(DateFormat, s, loc)
+		if err != nil {
+			writeError(w, "Некорректная граница периода", http.StatusBadRequest)
+			return
+		}
+		opts.From = t.UTC().Format(time.RFC3339)
+	}
+	if s := r.FormValue("to"); s != "" {
+		t, err := time.ParseInLocation(DateFormat, s, loc)
+		if err != nil {
+			writeError(w, "Некорректная граница периода", http.StatusBadRequest)
+			return
+		}
+		// Включаем весь день to
+		opts.To = t.AddDate(0, 0, 1).UTC().Format(time.RFC3339)
+	}
+
+	page, err := a.store.Completions(opts)
+	if errors.Is(err, db.ErrBadCursor) {
+		writeError(w, "Некорректный курсор: запросите первую страницу заново", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, CompletionsResp{Completions: page.Completions, NextCursor: page.NextCursor}, http.StatusOK)
+}
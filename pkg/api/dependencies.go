@@ -0,0 +1,52 @@
+package api
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+// MaxTaskDeps — наибольшее число задач, от которых может зависеть задача
+const MaxTaskDeps = 50
+
+// checkTaskDeps проверяет, что задачи из depends_on существуют и не совпадают с самой задачей,
+// и возвращает их по возрастанию без повторов. nil остаётся nil: при изменении задачи это значит
+// «зависимости не менять». Циклы через другие задачи находит хранилище.
+func (a *API) checkTaskDeps(task *db.Task) error {
+	// blocked_by и blocks вычисляются хранилищем и из запроса не принимаются
+	task.BlockedBy, task.Blocks = nil, nil
+	if task.DependsOn == nil {
+		return nil
+	}
+	if len(task.DependsOn) > MaxTaskDeps {
+		return errors.New("Слишком много зависимостей у задачи")
+	}
+
+	deps := make([]string, 0, len(task.DependsOn))
+	for _, dep := range task.DependsOn {
+		dep = strings.TrimSpace(dep)
+		n, err := strconv.ParseInt(dep, 10, 64)
+		if err != nil || n <= 0 {
+			return errors.New("Некорректный идентификатор задачи в depends_on: " + dep)
+		}
+		dep = strconv.FormatInt(n, 10)
+		if dep == task.ID {
+			return errors.New("Задача не может зависеть от самой себя")
+		}
+		if _, err := a.store.GetTask(dep); err != nil {
+			return errors.New("Задача из depends_on не найдена: " + dep)
+		}
+		deps = append(deps, dep)
+	}
+	slices.SortFunc(deps, func(x, y string) int {
+		nx, _ := strconv.ParseInt(x, 10, 64)
+		ny, _ := strconv.ParseInt(y, 10, 64)
+		return cmp.Compare(nx, ny)
+	})
+	task.DependsOn = slices.Compact(deps)
+	return nil
+}
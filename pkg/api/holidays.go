@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+type HolidaysResp struct {
+	Holidays []*db.Holiday `json:"holidays"`
+}
+
+func (a *API) holidaysHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.listHolidaysHandler(w, r)
+	case http.MethodPost:
+		a.addHolidayHandler(w, r)
+	case http.MethodDelete:
+		a.deleteHolidayHandler(w, r)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *API) listHolidaysHandler(w http.ResponseWriter, r *http.Request) {
+	from := r.FormValue("from")
+	to := r.FormValue("to")
+	for _, d := range []string{from, to} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse(DateFormat, d); err != nil {
+			writeError(w, "Некорректная граница периода", http.StatusBadRequest)
+			return
+		}
+	}
+
+	holidays, err := a.store.Holidays(from, to)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, HolidaysResp{Holidays: holidays}, http.StatusOK)
+}
+
+func (a *API) addHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	var holiday db.Holiday
+
+	if err := json.NewDecoder(r.Body).Decode(&holiday); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if _, err := time.Parse(DateFormat, holiday.Date); err != nil {
+		writeError(w, "Некорректная дата праздника", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.store.AddHoliday(&holiday); err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
+
+func (a *API) deleteHolidayHandler(w http.ResponseWriter, r *http.Request) {
+	date := r.FormValue("date")
+	if date == "" {
+		writeError(w, "Не указана дата", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.store.DeleteHoliday(date); err != nil {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+const (
+	// MaxItemTitleLength — наибольшая длина пункта чек-листа в символах
+	MaxItemTitleLength = 255
+	// MaxTaskItems — наибольшее число пунктов в чек-листе одной задачи
+	MaxTaskItems = 100
+)
+
+type TaskItemsResp struct {
+	Items []*db.TaskItem `json:"items"`
+}
+
+// checkItem проверяет название пункта чек-листа
+func checkItem(item *db.TaskItem) error {
+	item.Title = strings.TrimSpace(item.Title)
+	if item.Title == "" {
+		return errors.New("Не указано название пункта")
+	}
+	if utf8.RuneCountInString(item.Title) > MaxItemTitleLength {
+		return errors.New("Слишком длинное название пункта")
+	}
+	if item.Position < 0 {
+		return errors.New("Позиция пункта не может быть отрицательной")
+	}
+	return nil
+}
+
+// checkTaskItems проверяет чек-лист новой задачи; пункты получают позиции по порядку
+func checkTaskItems(task *db.Task) error {
+	if len(task.Items) > MaxTaskItems {
+		return errors.New("Слишком много пунктов в чек-листе")
+	}
+	for i, item := range task.Items {
+		if item == nil {
+			return errors.New("Не указано название пункта")
+		}
+		if err := checkItem(item); err != nil {
+			return err
+		}
+		item.ID = ""
+		item.Position = i + 1
+	}
+	return nil
+}
+
+// checkItemTask проверяет, что пункт id существует, а его задача не в корзине
+func (a *API) checkItemTask(id string) error {
+	item, err := a.store.GetTaskItem(id)
+	if err != nil {
+		return notFound("Пункт не найден")
+	}
+	if _, err := a.store.GetTask(item.TaskID); err != nil {
+		return notFound("Задача не найдена")
+	}
+	return nil
+}
+
+func (a *API) taskItemsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.getTaskItemsHandler(w, r)
+	case http.MethodPost:
+		a.addTaskItemHandler(w, r)
+	case http.MethodPut:
+		a.updateTaskItemHandler(w, r)
+	case http.MethodDelete:
+		a.deleteTaskItemHandler(w, r)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// getTaskItemsHandler возвращает чек-лист задачи task_id по порядку
+func (a *API) getTaskItemsHandler(w http.ResponseWriter, r *http.Request) {
+	taskID := r.FormValue("task_id")
+	if taskID == "" {
+		writeError(w, "Не указан идентификатор задачи", http.StatusBadRequest)
+		return
+	}
+	if _, err := a.store.GetTask(taskID); err != nil {
+		writeError(w, "Задача не найдена", http.StatusNotFound)
+		return
+	}
+
+	items, err := a.store.TaskItems(taskID)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, TaskItemsResp{Items: items}, http.StatusOK)
+}
+
+// addTaskItemHandler добавляет пункт в конец чек-листа
+func (a *API) addTaskItemHandler(w http.ResponseWriter, r *http.Request) {
+	var item db.TaskItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if item.TaskID == "" {
+		writeError(w, "Не указан идентификатор задачи", http.StatusBadRequest)
+		return
+	}
+	if err := checkItem(&item); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := a.store.GetTask(item.TaskID); err != nil {
+		writeError(w, "Задача не найдена", http.StatusNotFound)
+		return
+	}
+	items, err := a.store.TaskItems(item.TaskID)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(items) >= MaxTaskItems {
+		writeError(w, "Слишком много пунктов в чек-листе", http.StatusBadRequest)
+		return
+	}
+
+	id, err := a.store.AddTaskItem(&item)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{"id": strconv.FormatInt(id, 10)}, http.StatusOK)
+}
+
+// updateTaskItemHandler изменяет название, отметку и, если указана position, место пункта
+func (a *API) updateTaskItemHandler(w http.ResponseWriter, r *http.Request) {
+	var item db.TaskItem
+	if err := json.NewDecoder(r.Body).Decode(&item); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if item.ID == "" {
+		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
+		return
+	}
+	if err := checkItem(&item); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := a.checkItemTask(item.ID); err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	if err := a.store.UpdateTaskItem(&item); err != nil {
+		writeError(w, "Пункт не найден", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
+
+func (a *API) deleteTaskItemHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if id == "" {
+		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
+		return
+	}
+	if err := a.checkItemTask(id); err != nil {
+		writeTaskError(w, err)
+		return
+	}
+
+	if err := a.store.DeleteTaskItem(id); err != nil {
+		writeError(w, "Пункт не найден", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
@@ -9,7 +9,16 @@ import (
 	"time"
 )
 
-const DateFormat = "20060102"
+const (
+	DateFormat = "20060102"
+
+	DefaultNextDatesCount = 10
+	MaxNextDatesCount     = 100
+)
+
+type NextDatesResp struct {
+	Dates []string `json:"dates"`
+}
 
 // afterNow возвращает true, если d > now (только по дате, без учёта времени)
 func afterNow(d, now time.Time) bool {
@@ -45,8 +54,126 @@ func parseIntList(s string) ([]int, error) {
 	return result, nil
 }
 
-// NextDate вычисляет следующую дату задачи по правилам повторения
+// isoWeekday преобразует 1-7 (понедельник-воскресенье) в time.Weekday (0-6, воскресенье-суббота)
+func isoWeekday(wd int) time.Weekday {
+	if wd == 7 {
+		return time.Sunday
+	}
+	return time.Weekday(wd)
+}
+
+// parseMonths парсит необязательный список месяцев из третьей части правила.
+// Если месяцы не указаны, допустимы все месяцы.
+func parseMonths(parts []string, rule string) (map[int]bool, error) {
+	var months []int
+	if len(parts) >= 3 {
+		var err error
+		months, err = parseIntList(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid month list in %s rule", rule)
+		}
+	}
+
+	validMonths := make(map[int]bool)
+	for _, m := range months {
+		// Проверяем корректность месяцев
+		if m < 1 || m > 12 {
+			return nil, errors.New("month must be between 1 and 12")
+		}
+		validMonths[m] = true
+	}
+	if len(validMonths) == 0 {
+		for m := 1; m <= 12; m++ {
+			validMonths[m] = true
+		}
+	}
+	return validMonths, nil
+}
+
+// maxMonthDays — наибольшее число дней в каждом месяце (с учётом високосного февраля)
+var maxMonthDays = [13]int{0, 31, 29, 31, 30, 31, 30, 31, 31, 30, 31, 30, 31}
+
+// daysFitMonths проверяет, что хотя бы один день из списка встречается в одном из допустимых месяцев
+func daysFitMonths(days []int, validMonths map[int]bool) bool {
+	for _, d := range days {
+		if d < 0 {
+			d = -d
+		}
+		for m := range validMonths {
+			if d <= maxMonthDays[m] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// weekdayPosition — порядковый день недели в месяце: 1:1 — первый понедельник, -1:5 — последняя пятница
+type weekdayPosition struct {
+	pos     int
+	weekday time.Weekday
+}
+
+// parseWeekdayPositions парсит список вида 1:1,-1:5
+func parseWeekdayPositions(s string) ([]weekdayPosition, error) {
+	var result []weekdayPosition
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		posStr, wdStr, ok := strings.Cut(item, ":")
+		if !ok {
+			return nil, errors.New("invalid weekday position list in mw rule")
+		}
+		pos, err := strconv.Atoi(posStr)
+		if err != nil {
+			return nil, errors.New("invalid weekday position list in mw rule")
+		}
+		wd, err := strconv.Atoi(wdStr)
+		if err != nil {
+			return nil, errors.New("invalid weekday position list in mw rule")
+		}
+		if pos == 0 || pos < -5 || pos > 5 {
+			return nil, errors.New("weekday position must be between 1 and 5, or -1 and -5")
+		}
+		if wd < 1 || wd > 7 {
+			return nil, errors.New("weekday must be between 1 and 7")
+		}
+		result = append(result, weekdayPosition{pos: pos, weekday: isoWeekday(wd)})
+	}
+	if len(result) == 0 {
+		return nil, errors.New("weekday position list is empty in mw rule")
+	}
+	return result, nil
+}
+
+// matchWeekdayPosition проверяет, является ли дата одним из порядковых дней недели в своём месяце
+func matchWeekdayPosition(date time.Time, positions []weekdayPosition) bool {
+	day := date.Day()
+	lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+	for _, p := range positions {
+		if p.weekday != date.Weekday() {
+			continue
+		}
+		if p.pos > 0 && (day-1)/7+1 == p.pos {
+			return true
+		}
+		if p.pos < 0 && (lastDay-day)/7+1 == -p.pos {
+			return true
+		}
+	}
+	return false
+}
+
+// NextDate вычисляет следующую дату задачи по правилам повторения.
+// Календарь праздников не учитывается: рабочими считаются все будние дни.
 func NextDate(now time.Time, dstart string, repeat string) (string, error) {
+	return nextDate(now, dstart, repeat, noHolidays)
+}
+
+// nextDate вычисляет следующую дату задачи; праздники для правил с рабочими днями берёт loadHolidays
+func nextDate(now time.Time, dstart string, repeat string, loadHolidays holidaySource) (string, error) {
 	if repeat == "" {
 		return "", errors.New("repeat rule is empty")
 	}
@@ -55,7 +182,28 @@ func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 		return "", fmt.Errorf("invalid start date: %w", err)
 	}
 
+	// Правила в формате RFC 5545 обрабатываются отдельно
+	if isRRule(repeat) {
+		return nextRRule(now, date, repeat)
+	}
+
+	// Число повторений учитывается при отметке выполнения, здесь важна только дата окончания
+	repeat, end, err := splitRepeatEnd(repeat)
+	if err != nil {
+		return "", err
+	}
+
 	parts := strings.Split(repeat, " ")
+
+	// Правила с рабочими днями учитывают календарь праздников
+	var holidays map[string]bool
+	if usesBusinessDays(parts) {
+		holidays, err = loadHolidays()
+		if err != nil {
+			return "", err
+		}
+	}
+
 	switch parts[0] {
 	case "d":
 		if len(parts) != 2 {
@@ -76,6 +224,28 @@ func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 			}
 		}
 
+	case "bd":
+		if len(parts) != 2 {
+			return "", errors.New("invalid format for bd rule")
+		}
+		interval, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return "", errors.New("invalid interval in bd rule")
+		}
+		if interval < 1 || interval > 400 {
+			return "", errors.New("bd interval must be between 1 and 400")
+		}
+		// Всегда добавляем интервал рабочих дней хотя бы раз, затем продолжаем пока date <= now
+		for {
+			date, err = addBusinessDays(date, interval, holidays)
+			if err != nil {
+				return "", err
+			}
+			if afterNow(date, now) {
+				break
+			}
+		}
+
 	case "y":
 		origMonth := date.Month()
 		origDay := date.Day()
@@ -113,14 +283,7 @@ func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 		// Создаём карту допустимых дней недели
 		validWeekdays := make(map[time.Weekday]bool)
 		for _, wd := range weekdays {
-			// Преобразуем 1-7 (понедельник-воскресенье) в time.Weekday (0-6, воскресенье-суббота)
-			var weekday time.Weekday
-			if wd == 7 {
-				weekday = time.Sunday
-			} else {
-				weekday = time.Weekday(wd)
-			}
-			validWeekdays[weekday] = true
+			validWeekdays[isoWeekday(wd)] = true
 		}
 		// Ищем следующую подходящую дату
 		for {
@@ -134,58 +297,57 @@ func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 		if len(parts) < 2 {
 			return "", errors.New("invalid format for m rule")
 		}
-		days, err := parseIntList(parts[1])
+		days, businessDays, err := parseMonthDays(parts[1])
 		if err != nil {
-			return "", errors.New("invalid day list in m rule")
+			return "", err
 		}
-		if len(days) == 0 {
+		if len(days) == 0 && len(businessDays) == 0 {
 			return "", errors.New("day list is empty in m rule")
 		}
 		// Проверяем корректность дней месяца
 		for _, d := range days {
-			if d == -3 || d < -2 || d == 0 || d > 31 {
-				return "", errors.New("day must be between 1 and 31, or -1, -2")
+			if d < -31 || d == 0 || d > 31 {
+				return "", errors.New("day must be between 1 and 31, or -1 and -31")
 			}
 		}
-
-		var months []int
-		if len(parts) >= 3 {
-			months, err = parseIntList(parts[2])
-			if err != nil {
-				return "", errors.New("invalid month list in m rule")
-			}
-			// Проверяем корректность месяцев
-			for _, m := range months {
-				if m < 1 || m > 12 {
-					return "", errors.New("month must be between 1 and 12")
-				}
+		for _, d := range businessDays {
+			if d < -maxBusinessDays || d == 0 || d > maxBusinessDays {
+				return "", errors.New("business day must be between 1b and 23b, or -1b and -23b")
 			}
 		}
 
-		// Создаём карты допустимых дней и месяцев
+		validMonths, err := parseMonths(parts, "m")
+		if err != nil {
+			return "", err
+		}
+
+		// Создаём карты допустимых дней
 		validDays := make(map[int]bool)
 		for _, d := range days {
 			validDays[d] = true
 		}
+		validBusinessDays := make(map[int]bool)
+		for _, d := range businessDays {
+			validBusinessDays[d] = true
+		}
 
-		validMonths := make(map[int]bool)
-		if len(months) > 0 {
-			for _, m := range months {
-				validMonths[m] = true
-			}
-		} else {
-			// Если месяцы не указаны, все месяцы допустимы
-			for m := 1; m <= 12; m++ {
-				validMonths[m] = true
-			}
+		// Хотя бы один день должен существовать в одном из месяцев, иначе дата никогда не наступит
+		if len(businessDays) == 0 && !daysFitMonths(days, validMonths) {
+			return "", errors.New("day list does not fit any month in m rule")
 		}
 
 		// Ищем следующую подходящую дату
+		searched := 0
 		for {
 			date = date.AddDate(0, 0, 1)
 			if !afterNow(date, now) {
 				continue
 			}
+			// Праздники могут закрыть все рабочие дни, поэтому ограничиваем поиск
+			searched++
+			if searched > maxSearchDays {
+				return "", errors.New("no matching date found for m rule")
+			}
 
 			month := int(date.Month())
 			if !validMonths[month] {
@@ -199,27 +361,60 @@ func NextDate(now time.Time, dstart string, repeat string) (string, error) {
 				break
 			}
 
-			// Проверяем отрицательные дни (-1, -2)
-			if validDays[-1] || validDays[-2] {
-				// Находим последний день месяца
-				lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
-				if validDays[-1] && day == lastDay {
-					break
-				}
-				if validDays[-2] && day == lastDay-1 {
+			// Проверяем отрицательные дни: -1 — последний день месяца, -2 — предпоследний и т.д.
+			lastDay := time.Date(date.Year(), date.Month()+1, 0, 0, 0, 0, 0, date.Location()).Day()
+			if validDays[day-lastDay-1] {
+				break
+			}
+
+			// Проверяем рабочие дни: 1b — первый рабочий день месяца, -1b — последний
+			if len(validBusinessDays) > 0 && isBusinessDay(date, holidays) {
+				first, last := businessDayPosition(date, holidays)
+				if validBusinessDays[first] || validBusinessDays[-last] {
 					break
 				}
 			}
 		}
 
+	case "mw":
+		if len(parts) < 2 {
+			return "", errors.New("invalid format for mw rule")
+		}
+		positions, err := parseWeekdayPositions(parts[1])
+		if err != nil {
+			return "", err
+		}
+		validMonths, err := parseMonths(parts, "mw")
+		if err != nil {
+			return "", err
+		}
+
+		// Ищем следующую подходящую дату
+		for {
+			date = date.AddDate(0, 0, 1)
+			if !afterNow(date, now) {
+				continue
+			}
+			if !validMonths[int(date.Month())] {
+				continue
+			}
+			if matchWeekdayPosition(date, positions) {
+				break
+			}
+		}
+
 	default:
 		return "", errors.New("unsupported repeat rule")
 	}
 
-	return date.Format(DateFormat), nil
+	next := date.Format(DateFormat)
+	if end.until != "" && next > end.until {
+		return "", ErrRecurrenceEnded
+	}
+	return next, nil
 }
 
-func nextDayHandler(w http.ResponseWriter, r *http.Request) {
+func (a *API) nextDayHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -233,7 +428,11 @@ func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 	var err error
 
 	if nowStr == "" {
-		now = time.Now()
+		now, err = requestNow(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 	} else {
 		now, err = time.Parse(DateFormat, nowStr)
 		if err != nil {
@@ -242,7 +441,7 @@ func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	next, err := NextDate(now, dateStr, repeat)
+	next, err := a.nextDate(now, dateStr, repeat)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -251,3 +450,87 @@ func nextDayHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.Write([]byte(next))
 }
+
+// nextDatesHandler обрабатывает GET /api/nextdates и возвращает ближайшие даты по правилу
+func (a *API) nextDatesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	nowStr := r.FormValue("now")
+	dateStr := r.FormValue("date")
+	repeat := r.FormValue("repeat")
+
+	now, err := requestNow(r)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if nowStr != "" {
+		now, err = time.Parse(DateFormat, nowStr)
+		if err != nil {
+			writeError(w, "invalid now date format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	count := DefaultNextDatesCount
+	if countStr := r.FormValue("count"); countStr != "" {
+		count, err = strconv.Atoi(countStr)
+		if err != nil || count < 1 {
+			writeError(w, "count must be a positive number", http.StatusBadRequest)
+			return
+		}
+		if count > MaxNextDatesCount {
+			count = MaxNextDatesCount
+		}
+	}
+
+	until := r.FormValue("until")
+	if until != "" {
+		if _, err := time.Parse(DateFormat, until); err != nil {
+			writeError(w, "invalid until date format", http.StatusBadRequest)
+			return
+		}
+	}
+
+	dates, err := a.nextDates(now, dateStr, repeat, count, until)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, NextDatesResp{Dates: dates}, http.StatusOK)
+}
+
+// nextDates возвращает до count следующих дат по правилу, не позже until (если задан).
+// Дата начала не меняется, сдвигается только «сегодня», поэтому COUNT и UNTIL учитываются корректно.
+func (a *API) nextDates(now time.Time, dstart string, repeat string, count int, until string) ([]string, error) {
+	// При ограничении xN первой из N дат считается сама дата начала
+	if _, end, err := splitRepeatEnd(repeat); err == nil && end.count > 0 && count > end.count-1 {
+		count = end.count - 1
+	}
+
+	dates := make([]string, 0, count)
+	for len(dates) < count {
+		next, err := a.nextDate(now, dstart, repeat)
+		if err != nil {
+			// Правило закончилось после хотя бы одной даты — это не ошибка
+			if errors.Is(err, ErrRecurrenceEnded) && len(dates) > 0 {
+				break
+			}
+			return nil, err
+		}
+		if until != "" && next > until {
+			break
+		}
+		dates = append(dates, next)
+
+		now, err = time.Parse(DateFormat, next)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return dates, nil
+}
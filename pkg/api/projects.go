@@ -0,0 +1,172 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+// MaxProjectNameLength — наибольшая длина названия проекта в символах
+const MaxProjectNameLength = 255
+
+// projectColor — цвет проекта в формате #rrggbb
+var projectColor = regexp.MustCompile(`^#[0-9a-f]{6}$`)
+
+type ProjectsResp struct {
+	Projects []*db.Project `json:"projects"`
+}
+
+// checkProject проверяет название и цвет проекта
+func checkProject(p *db.Project) error {
+	p.Name = strings.TrimSpace(p.Name)
+	if p.Name == "" {
+		return errors.New("Не указано название проекта")
+	}
+	if utf8.RuneCountInString(p.Name) > MaxProjectNameLength {
+		return errors.New("Слишком длинное название проекта")
+	}
+	p.Color = strings.ToLower(p.Color)
+	if p.Color != "" && !projectColor.MatchString(p.Color) {
+		return errors.New("Цвет проекта должен быть в формате #rrggbb")
+	}
+	return nil
+}
+
+// checkTaskProject проверяет, что проект задачи существует
+func (a *API) checkTaskProject(task *db.Task) error {
+	if task.ProjectID == "" {
+		return nil
+	}
+	if _, err := a.store.GetProject(task.ProjectID); err != nil {
+		return errors.New("Проект не найден")
+	}
+	return nil
+}
+
+func (a *API) projectsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.getProjectsHandler(w, r)
+	case http.MethodPost:
+		a.addProjectHandler(w, r)
+	case http.MethodPut:
+		a.updateProjectHandler(w, r)
+	case http.MethodDelete:
+		a.deleteProjectHandler(w, r)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// getProjectsHandler возвращает проект по id или список проектов; архивные — с archived=true
+func (a *API) getProjectsHandler(w http.ResponseWriter, r *http.Request) {
+	if id := r.FormValue("id"); id != "" {
+		project, err := a.store.GetProject(id)
+		if err != nil {
+			writeError(w, "Проект не найден", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, project, http.StatusOK)
+		return
+	}
+
+	projects, err := a.store.Projects(r.FormValue("archived") == "true")
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, ProjectsResp{Projects: projects}, http.StatusOK)
+}
+
+func (a *API) addProjectHandler(w http.ResponseWriter, r *http.Request) {
+	var project db.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := checkProject(&project); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	id, err := a.store.AddProject(&project)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{"id": strconv.FormatInt(id, 10)}, http.StatusOK)
+}
+
+func (a *API) updateProjectHandler(w http.ResponseWriter, r *http.Request) {
+	var project db.Project
+	if err := json.NewDecoder(r.Body).Decode(&project); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if project.ID == "" {
+		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
+		return
+	}
+	if err := checkProject(&project); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := a.store.UpdateProject(&project); err != nil {
+		writeError(w, "Проект не найден", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
+
+// deleteProjectHandler обрабатывает DELETE /api/projects?id=&move_to=&tasks=.
+// Задачи проекта переходят в проект move_to, с tasks=delete — в корзину, иначе остаются без проекта.
+func (a *API) deleteProjectHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if id == "" {
+		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
+		return
+	}
+
+	moveTo := r.FormValue("move_to")
+	trashTasks := false
+	switch r.FormValue("tasks") {
+	case "":
+	case "delete":
+		trashTasks = true
+	default:
+		writeError(w, "Параметр tasks принимает только значение delete", http.StatusBadRequest)
+		return
+	}
+	if trashTasks && moveTo != "" {
+		writeError(w, "Укажите либо move_to, либо tasks=delete", http.StatusBadRequest)
+		return
+	}
+	if moveTo != "" {
+		if moveTo == id {
+			writeError(w, "Нельзя перенести задачи в удаляемый проект", http.StatusBadRequest)
+			return
+		}
+		if _, err := a.store.GetProject(moveTo); err != nil {
+			writeError(w, "Проект move_to не найден", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := a.store.DeleteProject(id, moveTo, trashTasks); err != nil {
+		writeError(w, "Проект не найден", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
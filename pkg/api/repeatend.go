@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// repeatEnd — необязательное окончание правила повторения:
+// последняя допустимая дата (until 20261231) и/или общее число повторений (x10)
+type repeatEnd struct {
+	until string
+	count int
+}
+
+// splitRepeatEnd отделяет от правила суффиксы окончания и возвращает правило без них.
+// Правила RFC 5545 задают окончание через COUNT и UNTIL и возвращаются как есть.
+func splitRepeatEnd(repeat string) (string, repeatEnd, error) {
+	var end repeatEnd
+	if isRRule(repeat) {
+		return repeat, end, nil
+	}
+
+	parts := strings.Split(repeat, " ")
+	for len(parts) > 1 {
+		last := parts[len(parts)-1]
+
+		if len(parts) > 2 && parts[len(parts)-2] == "until" {
+			if end.until != "" {
+				return "", end, errors.New("until is specified more than once")
+			}
+			if _, err := time.Parse(DateFormat, last); err != nil {
+				return "", end, errors.New("invalid until date")
+			}
+			end.until = last
+			parts = parts[:len(parts)-2]
+			continue
+		}
+
+		if strings.HasPrefix(last, "x") {
+			if end.count != 0 {
+				return "", end, errors.New("repeat count is specified more than once")
+			}
+			count, err := strconv.Atoi(last[1:])
+			if err != nil || count < 1 {
+				return "", end, errors.New("repeat count must be a positive number")
+			}
+			end.count = count
+			parts = parts[:len(parts)-1]
+			continue
+		}
+
+		break
+	}
+
+	return strings.Join(parts, " "), end, nil
+}
+
+// repeatCount возвращает общее число повторений правила или 0, если оно не ограничено
+func repeatCount(repeat string) int {
+	if isRRule(repeat) {
+		r, err := parseRRule(repeat)
+		if err != nil {
+			return 0
+		}
+		return r.count
+	}
+
+	_, end, err := splitRepeatEnd(repeat)
+	if err != nil {
+		return 0
+	}
+	return end.count
+}
@@ -0,0 +1,480 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rrulePrefix = "RRULE:"
+	// rruleMaxPeriods ограничивает перебор периодов для правил, которые никогда не срабатывают
+	rruleMaxPeriods = 100000
+)
+
+// ErrRecurrenceEnded возвращается, когда у правила повторения больше нет дат
+var ErrRecurrenceEnded = errors.New("recurrence has no more occurrences")
+
+var weekdayCodes = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// weekdayNum — элемент BYDAY: день недели с необязательным порядковым номером (+1MO, -1FR)
+type weekdayNum struct {
+	n       int
+	weekday time.Weekday
+}
+
+// rrule — разобранное правило повторения в формате RFC 5545
+type rrule struct {
+	freq       string
+	interval   int
+	count      int
+	until      time.Time
+	byDay      []weekdayNum
+	byMonthDay []int
+	byMonth    []int
+	bySetPos   []int
+	wkst       time.Weekday
+}
+
+// isRRule возвращает true, если правило задано в формате RFC 5545
+func isRRule(repeat string) bool {
+	return strings.HasPrefix(strings.ToUpper(repeat), rrulePrefix)
+}
+
+// parseRRule разбирает строку вида RRULE:FREQ=MONTHLY;BYDAY=2TU
+func parseRRule(s string) (*rrule, error) {
+	body := strings.TrimSpace(s[len(rrulePrefix):])
+	if body == "" {
+		return nil, errors.New("RRULE is empty")
+	}
+
+	r := &rrule{interval: 1, wkst: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(body, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate RRULE part %s", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return nil, fmt.Errorf("unsupported RRULE frequency %s", value)
+			}
+		case "INTERVAL":
+			r.interval, err = strconv.Atoi(value)
+			if err != nil || r.interval < 1 || r.interval > 400 {
+				return nil, errors.New("RRULE interval must be between 1 and 400")
+			}
+		case "COUNT":
+			r.count, err = strconv.Atoi(value)
+			if err != nil || r.count < 1 {
+				return nil, errors.New("RRULE count must be a positive number")
+			}
+		case "UNTIL":
+			r.until, err = parseRRuleUntil(value)
+			if err != nil {
+				return nil, err
+			}
+		case "BYDAY":
+			r.byDay, err = parseByDay(value)
+			if err != nil {
+				return nil, err
+			}
+		case "BYMONTHDAY":
+			r.byMonthDay, err = parseRRuleInts(value, -31, 31)
+			if err != nil {
+				return nil, errors.New("RRULE month day must be between 1 and 31 or -31 and -1")
+			}
+		case "BYMONTH":
+			r.byMonth, err = parseRRuleInts(value, 1, 12)
+			if err != nil {
+				return nil, errors.New("RRULE month must be between 1 and 12")
+			}
+		case "BYSETPOS":
+			r.bySetPos, err = parseRRuleInts(value, -366, 366)
+			if err != nil {
+				return nil, errors.New("RRULE set position must be between 1 and 366 or -366 and -1")
+			}
+		case "WKST":
+			wd, ok := weekdayCodes[value]
+			if !ok {
+				return nil, fmt.Errorf("invalid RRULE week start %s", value)
+			}
+			r.wkst = wd
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %s", key)
+		}
+	}
+
+	if r.freq == "" {
+		return nil, errors.New("RRULE frequency is not specified")
+	}
+	if r.count > 0 && !r.until.IsZero() {
+		return nil, errors.New("RRULE count and until cannot be used together")
+	}
+	if len(r.byMonthDay) > 0 && r.freq == "WEEKLY" {
+		return nil, errors.New("RRULE month day cannot be used with weekly frequency")
+	}
+	if r.freq == "DAILY" || r.freq == "WEEKLY" {
+		for _, d := range r.byDay {
+			if d.n != 0 {
+				return nil, errors.New("RRULE weekday position is allowed only for monthly and yearly frequency")
+			}
+		}
+	}
+	if len(r.bySetPos) > 0 && len(r.byDay) == 0 && len(r.byMonthDay) == 0 && len(r.byMonth) == 0 {
+		return nil, errors.New("RRULE set position requires another BY rule")
+	}
+
+	return r, nil
+}
+
+// parseRRuleUntil разбирает UNTIL в виде даты или даты со временем
+func parseRRuleUntil(value string) (time.Time, error) {
+	if len(value) >= 8 {
+		if t, err := time.Parse(DateFormat, value[:8]); err == nil {
+			rest := value[8:]
+			if rest == "" || ((len(rest) == 7 || len(rest) == 8) && rest[0] == 'T') {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid RRULE until %s", value)
+}
+
+// parseRRuleInts парсит список чисел через запятую, исключая ноль и значения вне диапазона
+func parseRRuleInts(value string, min, max int) ([]int, error) {
+	nums, err := parseIntList(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(nums) == 0 {
+		return nil, errors.New("list is empty")
+	}
+	for _, n := range nums {
+		if n == 0 || n < min || n > max {
+			return nil, errors.New("value out of range")
+		}
+	}
+	return nums, nil
+}
+
+// parseByDay парсит BYDAY: список дней недели с необязательными порядковыми номерами
+func parseByDay(value string) ([]weekdayNum, error) {
+	var result []weekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid RRULE weekday %q", item)
+		}
+		wd, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid RRULE weekday %q", item)
+		}
+		var n int
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("invalid RRULE weekday position %q", item)
+			}
+		}
+		result = append(result, weekdayNum{n: n, weekday: wd})
+	}
+	return result, nil
+}
+
+// nextRRule вычисляет следующую дату по правилу RFC 5545
+func nextRRule(now, start time.Time, repeat string) (string, error) {
+	r, err := parseRRule(repeat)
+	if err != nil {
+		return "", err
+	}
+
+	now = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Без COUNT можно пропустить периоды, которые целиком лежат в прошлом
+	first := 0
+	if r.count == 0 {
+		target := now
+		if start.After(target) {
+			target = start
+		}
+		first = r.periodsBetween(start, target)/r.interval - 1
+		if first < 0 {
+			first = 0
+		}
+	}
+
+	found := 0
+	for i := first; i < first+rruleMaxPeriods; i++ {
+		candidates := r.expand(r.periodStart(start, i), start)
+		if len(candidates) > 0 && candidates[0].Year() > 9999 {
+			break
+		}
+		for _, c := range candidates {
+			if c.Before(start) {
+				continue
+			}
+			if !r.until.IsZero() && c.After(r.until) {
+				return "", ErrRecurrenceEnded
+			}
+			found++
+			if r.count > 0 && found > r.count {
+				return "", ErrRecurrenceEnded
+			}
+			if afterNow(c, now) && afterNow(c, start) {
+				return c.Format(DateFormat), nil
+			}
+		}
+	}
+
+	return "", errors.New("RRULE has no occurrences in a reasonable range")
+}
+
+// periodsBetween возвращает число целых периодов частоты между start и target
+func (r *rrule) periodsBetween(start, target time.Time) int {
+	switch r.freq {
+	case "DAILY":
+		return int((target.Unix() - start.Unix()) / 86400)
+	case "WEEKLY":
+		return int((target.Unix() - start.Unix()) / 86400 / 7)
+	case "MONTHLY":
+		return (target.Year()-start.Year())*12 + int(target.Month()) - int(start.Month())
+	default:
+		return target.Year() - start.Year()
+	}
+}
+
+// periodStart возвращает начало i-го периода (день, неделя, месяц или год)
+func (r *rrule) periodStart(start time.Time, i int) time.Time {
+	step := i * r.interval
+	switch r.freq {
+	case "DAILY":
+		return start.AddDate(0, 0, step)
+	case "WEEKLY":
+		shift := (int(start.Weekday()) - int(r.wkst) + 7) % 7
+		return start.AddDate(0, 0, 7*step-shift)
+	case "MONTHLY":
+		return time.Date(start.Year(), start.Month()+time.Month(step), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return time.Date(start.Year()+step, time.January, 1, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// expand возвращает отсортированные даты периода, подходящие под правило
+func (r *rrule) expand(period, start time.Time) []time.Time {
+	var dates []time.Time
+	switch r.freq {
+	case "DAILY":
+		if r.matchMonth(period) && r.matchMonthDay(period) && r.matchWeekday(period) {
+			dates = append(dates, period)
+		}
+	case "WEEKLY":
+		for d := 0; d < 7; d++ {
+			day := period.AddDate(0, 0, d)
+			if !r.matchMonth(day) {
+				continue
+			}
+			if len(r.byDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if r.matchWeekday(day) {
+				dates = append(dates, day)
+			}
+		}
+	case "MONTHLY":
+		if r.matchMonth(period) {
+			dates = r.monthDates(period.Year(), period.Month(), start)
+		}
+	default:
+		dates = r.yearDates(period.Year(), start)
+	}
+
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	dates = uniqueDates(dates)
+	if len(r.bySetPos) > 0 {
+		dates = applySetPos(dates, r.bySetPos)
+	}
+	return dates
+}
+
+// monthDates возвращает даты месяца по BYMONTHDAY и BYDAY
+func (r *rrule) monthDates(year int, month time.Month, start time.Time) []time.Time {
+	last := daysIn(year, month)
+	var dates []time.Time
+
+	switch {
+	case len(r.byMonthDay) > 0:
+		for _, d := range r.byMonthDay {
+			if d < 0 {
+				d = last + 1 + d
+			}
+			if d < 1 || d > last {
+				continue
+			}
+			day := time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+			if r.matchWeekday(day) {
+				dates = append(dates, day)
+			}
+		}
+	case len(r.byDay) > 0:
+		first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+		dates = nthWeekdays(first, last, r.byDay)
+	default:
+		if start.Day() <= last {
+			dates = append(dates, time.Date(year, month, start.Day(), 0, 0, 0, 0, time.UTC))
+		}
+	}
+	return dates
+}
+
+// yearDates возвращает даты года по BYMONTH, BYMONTHDAY и BYDAY
+func (r *rrule) yearDates(year int, start time.Time) []time.Time {
+	var dates []time.Time
+
+	switch {
+	case len(r.byMonth) > 0:
+		for _, m := range r.byMonth {
+			month := time.Month(m)
+			if len(r.byMonthDay) > 0 || len(r.byDay) > 0 {
+				dates = append(dates, r.monthDates(year, month, start)...)
+			} else if start.Day() <= daysIn(year, month) {
+				dates = append(dates, time.Date(year, month, start.Day(), 0, 0, 0, 0, time.UTC))
+			}
+		}
+	case len(r.byMonthDay) > 0:
+		for m := time.January; m <= time.December; m++ {
+			dates = append(dates, r.monthDates(year, m, start)...)
+		}
+	case len(r.byDay) > 0:
+		// Без BYMONTH порядковые номера дней недели считаются внутри года
+		first := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		last := 365
+		if daysIn(year, time.February) == 29 {
+			last = 366
+		}
+		dates = nthWeekdays(first, last, r.byDay)
+	default:
+		if start.Day() <= daysIn(year, start.Month()) {
+			dates = append(dates, time.Date(year, start.Month(), start.Day(), 0, 0, 0, 0, time.UTC))
+		}
+	}
+	return dates
+}
+
+func (r *rrule) matchMonth(t time.Time) bool {
+	if len(r.byMonth) == 0 {
+		return true
+	}
+	for _, m := range r.byMonth {
+		if time.Month(m) == t.Month() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rrule) matchMonthDay(t time.Time) bool {
+	if len(r.byMonthDay) == 0 {
+		return true
+	}
+	last := daysIn(t.Year(), t.Month())
+	for _, d := range r.byMonthDay {
+		if d == t.Day() || (d < 0 && last+1+d == t.Day()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *rrule) matchWeekday(t time.Time) bool {
+	if len(r.byDay) == 0 {
+		return true
+	}
+	for _, d := range r.byDay {
+		if d.weekday == t.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+// nthWeekdays возвращает дни недели из списка внутри диапазона из total дней, начиная с first.
+// Порядковый номер n выбирает n-й такой день с начала (или с конца при n < 0), ноль — все дни.
+func nthWeekdays(first time.Time, total int, days []weekdayNum) []time.Time {
+	var dates []time.Time
+	for _, d := range days {
+		var matches []time.Time
+		offset := (int(d.weekday) - int(first.Weekday()) + 7) % 7
+		for day := offset; day < total; day += 7 {
+			matches = append(matches, first.AddDate(0, 0, day))
+		}
+		switch {
+		case d.n == 0:
+			dates = append(dates, matches...)
+		case d.n > 0 && d.n <= len(matches):
+			dates = append(dates, matches[d.n-1])
+		case d.n < 0 && -d.n <= len(matches):
+			dates = append(dates, matches[len(matches)+d.n])
+		}
+	}
+	return dates
+}
+
+// applySetPos оставляет из набора дат только позиции из BYSETPOS
+func applySetPos(dates []time.Time, positions []int) []time.Time {
+	var result []time.Time
+	for _, p := range positions {
+		idx := p - 1
+		if p < 0 {
+			idx = len(dates) + p
+		}
+		if idx >= 0 && idx < len(dates) {
+			result = append(result, dates[idx])
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	return uniqueDates(result)
+}
+
+func uniqueDates(dates []time.Time) []time.Time {
+	var result []time.Time
+	for _, d := range dates {
+		if len(result) == 0 || !d.Equal(result[len(result)-1]) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// daysIn возвращает количество дней в месяце
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
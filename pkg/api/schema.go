@@ -0,0 +1,27 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+type SchemaResp struct {
+	Version int `json:"version"`
+	Latest  int `json:"latest"`
+}
+
+// schemaHandler обрабатывает GET /api/schema и сообщает версию схемы базы данных
+func (a *API) schemaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	version, err := a.store.SchemaVersion()
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, SchemaResp{Version: version, Latest: db.LatestSchemaVersion()}, http.StatusOK)
+}
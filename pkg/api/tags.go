@@ -0,0 +1,143 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+// MaxTagLength — наибольшая длина тега в символах
+const MaxTagLength = 64
+
+type TagsResp struct {
+	Tags []*db.Tag `json:"tags"`
+}
+
+// RenameTagReq — тело PUT /api/tags
+type RenameTagReq struct {
+	Name    string `json:"name"`
+	NewName string `json:"new_name"`
+}
+
+// MergeTagsReq — тело POST /api/tags/merge
+type MergeTagsReq struct {
+	Tags []string `json:"tags"`
+	Into string   `json:"into"`
+}
+
+// normalizeTag приводит тег к нижнему регистру без ведущего «#».
+// Тег — одно слово: пробелы и запятые в нём не допускаются.
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+	if tag == "" {
+		return "", errors.New("Пустой тег")
+	}
+	if utf8.RuneCountInString(tag) > MaxTagLength {
+		return "", errors.New("Слишком длинный тег: " + tag)
+	}
+	if strings.ContainsFunc(tag, func(r rune) bool { return unicode.IsSpace(r) || r == ',' }) {
+		return "", errors.New("Тег не может содержать пробелы и запятые: " + tag)
+	}
+	return tag, nil
+}
+
+// normalizeTags проверяет теги задачи и возвращает их по алфавиту без повторов.
+// nil остаётся nil: при изменении задачи это значит «теги не менять».
+func normalizeTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, tag)
+	}
+	slices.Sort(normalized)
+	return slices.Compact(normalized), nil
+}
+
+func (a *API) tagsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.listTagsHandler(w, r)
+	case http.MethodPut:
+		a.renameTagHandler(w, r)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+func (a *API) listTagsHandler(w http.ResponseWriter, r *http.Request) {
+	tags, err := a.store.Tags()
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, TagsResp{Tags: tags}, http.StatusOK)
+}
+
+// renameTagHandler переименовывает тег; если тег с новым именем уже есть, теги объединяются
+func (a *API) renameTagHandler(w http.ResponseWriter, r *http.Request) {
+	var req RenameTagReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.mergeTags(w, []string{req.Name}, req.NewName)
+}
+
+// mergeTagsHandler обрабатывает POST /api/tags/merge: задачи с тегами tags получают тег into
+func (a *API) mergeTagsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req MergeTagsReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(req.Tags) == 0 {
+		writeError(w, "Не указаны объединяемые теги", http.StatusBadRequest)
+		return
+	}
+
+	a.mergeTags(w, req.Tags, req.Into)
+}
+
+func (a *API) mergeTags(w http.ResponseWriter, names []string, into string) {
+	names, err := normalizeTags(names)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	into, err = normalizeTag(into)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = a.store.MergeTags(names, into)
+	if errors.Is(err, db.ErrTagNotFound) {
+		writeError(w, "Тег не найден", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
@@ -1,26 +1,196 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Evrard-ro/final_project/pkg/db"
 )
 
 const (
 	DefaultTasksLimit = 50
+	MaxTasksLimit     = 500
 )
 
 type TasksResp struct {
 	Tasks []*db.Task `json:"tasks"`
+	// NextCursor передаётся в параметре cursor для получения следующей страницы
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Total — число задач по запросу без учёта страниц, если запрошено total=true
+	Total *int `json:"total,omitempty"`
 }
 
-func tasksHandler(w http.ResponseWriter, r *http.Request) {
-	search := r.FormValue("search")
+// Представления списка задач для параметра view
+const (
+	ViewToday   = "today"
+	ViewWeek    = "week"
+	ViewNext7   = "next7"
+	ViewOverdue = "overdue"
+)
+
+// viewRange возвращает границы дат представления включительно; now задаёт «сегодня».
+// Просроченные — все задачи раньше сегодняшнего дня, в том числе повторяющиеся:
+// их дата не сдвигается, пока задачу не отметят выполненной.
+func viewRange(view string, now time.Time) (from, to string, err error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch view {
+	case ViewToday:
+		return today.Format(DateFormat), today.Format(DateFormat), nil
+	case ViewWeek:
+		// Неделя с понедельника по воскресенье
+		monday := today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return monday.Format(DateFormat), monday.AddDate(0, 0, 6).Format(DateFormat), nil
+	case ViewNext7:
+		return today.Format(DateFormat), today.AddDate(0, 0, 6).Format(DateFormat), nil
+	case ViewOverdue:
+		return "", today.AddDate(0, 0, -1).Format(DateFormat), nil
+	}
+	return "", "", errors.New("Неизвестное представление: используйте today, week, next7 или overdue")
+}
+
+// parseLimit читает размер страницы из параметра limit; без него возвращает def
+func parseLimit(r *http.Request, def int) (int, error) {
+	s := r.FormValue("limit")
+	if s == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > MaxTasksLimit {
+		return 0, errors.New("Некорректный limit: допустимо от 1 до " + strconv.Itoa(MaxTasksLimit))
+	}
+	return n, nil
+}
+
+// parseRangeDate принимает границу периода в формате 20060102 или 02.01.2006
+func parseRangeDate(s string) (string, error) {
+	for _, layout := range []string{DateFormat, "02.01.2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format(DateFormat), nil
+		}
+	}
+	return "", errors.New("Некорректная граница периода")
+}
+
+// tasksHandler обрабатывает GET /api/tasks?search=&view=&from=&to=&tag=&priority=&project=&blocked=&sort=&limit=&cursor=&total=.
+// view (today, week, next7, overdue) и границы from, to отбирают задачи по дате.
+// sort — поля date, title, created, updated через запятую, минус перед полем — по убыванию.
+// tag отбирает задачи с тегом; при нескольких tag задача должна иметь их все.
+// priority — допустимые приоритеты через запятую, например priority=2,3.
+// project — задачи проекта, project=none — задачи без проекта.
+// blocked=false — только задачи, которые можно выполнять сейчас, blocked=true — только заблокированные.
+// Следующая страница запрашивается с cursor из next_cursor предыдущего ответа.
+func (a *API) tasksHandler(w http.ResponseWriter, r *http.Request) {
+	opts := db.TasksOptions{
+		Limit:  DefaultTasksLimit,
+		Search: r.FormValue("search"),
+		Sort:   r.FormValue("sort"),
+		Cursor: r.FormValue("cursor"),
+		Total:  r.FormValue("total") == "true",
+	}
+
+	for _, tag := range r.Form["tag"] {
+		tag, err := normalizeTag(tag)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		opts.Tags = append(opts.Tags, tag)
+	}
+
+	if priority := r.FormValue("priority"); priority != "" {
+		for _, p := range strings.Split(priority, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(p))
+			if err != nil || n < 0 || n > db.MaxPriority {
+				writeError(w, fmt.Sprintf("Некорректный приоритет: допустимо от 0 до %d", db.MaxPriority), http.StatusBadRequest)
+				return
+			}
+			opts.Priorities = append(opts.Priorities, n)
+		}
+	}
+
+	switch project := r.FormValue("project"); project {
+	case "":
+	case "none":
+		opts.ProjectID = "0"
+	default:
+		if n, err := strconv.ParseInt(project, 10, 64); err != nil || n < 0 {
+			writeError(w, "Некорректный идентификатор проекта", http.StatusBadRequest)
+			return
+		}
+		opts.ProjectID = project
+	}
 
-	tasks, err := db.Tasks(DefaultTasksLimit, search)
+	if blocked := r.FormValue("blocked"); blocked != "" {
+		if blocked != "true" && blocked != "false" {
+			writeError(w, "Параметр blocked принимает значения true или false", http.StatusBadRequest)
+			return
+		}
+		b := blocked == "true"
+		opts.Blocked = &b
+	}
+
+	limit, err := parseLimit(r, DefaultTasksLimit)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Limit = limit
+
+	from, to := r.FormValue("from"), r.FormValue("to")
+	if view := r.FormValue("view"); view != "" {
+		if from != "" || to != "" {
+			writeError(w, "Укажите либо view, либо from и to", http.StatusBadRequest)
+			return
+		}
+		now, err := requestNow(r)
+		if err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if opts.From, opts.To, err = viewRange(view, now); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if from != "" {
+		if opts.From, err = parseRangeDate(from); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if to != "" {
+		if opts.To, err = parseRangeDate(to); err != nil {
+			writeError(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := a.store.Tasks(opts)
+	if errors.Is(err, db.ErrBadSearch) {
+		writeError(w, "Некорректный поисковый запрос: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, db.ErrBadSort) {
+		writeError(w, "Некорректная сортировка: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, db.ErrBadCursor) {
+		writeError(w, "Некорректный курсор: запросите первую страницу заново", http.StatusBadRequest)
+		return
+	}
 	if err != nil {
 		writeError(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	writeJSON(w, TasksResp{Tasks: tasks}, http.StatusOK)
+
+	resp := TasksResp{Tasks: page.Tasks, NextCursor: page.NextCursor}
+	if opts.Total {
+		resp.Total = &page.Total
+	}
+	writeJSON(w, resp, http.StatusOK)
 }
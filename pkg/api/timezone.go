@@ -0,0 +1,62 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	// Встроенная база часовых поясов на случай, если в системе её нет
+	_ "time/tzdata"
+)
+
+// defaultLocation — часовой пояс, в котором определяется «сегодня», если запрос не указал свой
+var defaultLocation = time.Local
+
+// InitTimezone читает часовой пояс по умолчанию из переменной окружения TODO_TZ
+func InitTimezone() error {
+	name := os.Getenv("TODO_TZ")
+	if name == "" {
+		defaultLocation = time.Local
+		return nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return fmt.Errorf("invalid TODO_TZ %q: %w", name, err)
+	}
+	defaultLocation = loc
+	return nil
+}
+
+// requestLocation возвращает часовой пояс запроса: параметр tz, заголовок X-Timezone
+// или кука tz (сохраняется в браузере пользователя). Без них используется пояс по умолчанию.
+func requestLocation(r *http.Request) (*time.Location, error) {
+	name := r.FormValue("tz")
+	if name == "" {
+		name = r.Header.Get("X-Timezone")
+	}
+	if name == "" {
+		if cookie, err := r.Cookie("tz"); err == nil {
+			name = cookie.Value
+		}
+	}
+	if name == "" {
+		return defaultLocation, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("Некорректный часовой пояс %q", name)
+	}
+	return loc, nil
+}
+
+// requestNow возвращает текущий момент в часовом поясе запроса
+func requestNow(r *http.Request) (time.Time, error) {
+	loc, err := requestLocation(r)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(loc), nil
+}
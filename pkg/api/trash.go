@@ -0,0 +1,144 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+const (
+	DefaultTrashLimit = 50
+	// DefaultTrashRetention — сколько задачи хранятся в корзине перед окончательным удалением
+	DefaultTrashRetention = 30 * 24 * time.Hour
+	// trashPurgeInterval — как часто фоновая задача очищает корзину
+	trashPurgeInterval = time.Hour
+)
+
+var trashRetention = DefaultTrashRetention
+
+// InitTrash читает срок хранения корзины из TODO_TRASH_RETENTION (например, 72h)
+func InitTrash() error {
+	s := os.Getenv("TODO_TRASH_RETENTION")
+	if s == "" {
+		trashRetention = DefaultTrashRetention
+		return nil
+	}
+
+	retention, err := time.ParseDuration(s)
+	if err != nil || retention <= 0 {
+		return fmt.Errorf("invalid TODO_TRASH_RETENTION %q", s)
+	}
+	trashRetention = retention
+	return nil
+}
+
+// StartTrashPurger периодически удаляет задачи, пролежавшие в корзине дольше срока хранения
+func (a *API) StartTrashPurger() {
+	purge := func() {
+		count, err := a.store.PurgeTrash(time.Now().Add(-trashRetention))
+		if err != nil {
+			log.Printf("Ошибка очистки корзины: %v", err)
+			return
+		}
+		if count > 0 {
+			log.Printf("Из корзины удалено задач: %d", count)
+		}
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			purge()
+		}
+	}()
+}
+
+func (a *API) trashHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		a.listTrashHandler(w, r)
+	case http.MethodDelete:
+		a.purgeTrashHandler(w, r)
+	default:
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// listTrashHandler обрабатывает GET /api/trash?limit=&cursor=.
+// Следующая страница запрашивается с cursor из next_cursor предыдущего ответа.
+func (a *API) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	limit, err := parseLimit(r, DefaultTrashLimit)
+	if err != nil {
+		writeError(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page, err := a.store.TrashedTasks(limit, r.FormValue("cursor"))
+	if errors.Is(err, db.ErrBadCursor) {
+		writeError(w, "Некорректный курсор: запросите первую страницу заново", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, TasksResp{Tasks: page.Tasks, NextCursor: page.NextCursor}, http.StatusOK)
+}
+
+// purgeTrashHandler обрабатывает DELETE /api/trash?id= и окончательно удаляет задачу из корзины.
+// Корзина очищается целиком только явным DELETE /api/trash?all=true.
+func (a *API) purgeTrashHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.FormValue("id")
+	if r.FormValue("all") == "true" {
+		if id != "" {
+			writeError(w, "Укажите либо id, либо all=true", http.StatusBadRequest)
+			return
+		}
+		if _, err := a.store.EmptyTrash(); err != nil {
+			writeError(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, map[string]string{}, http.StatusOK)
+		return
+	}
+
+	if id == "" {
+		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.store.PurgeTrashedTask(id); err != nil {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
+
+// restoreTrashHandler обрабатывает POST /api/trash/restore?id=
+func (a *API) restoreTrashHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := r.FormValue("id")
+	if id == "" {
+		writeError(w, "Не указан идентификатор", http.StatusBadRequest)
+		return
+	}
+
+	if err := a.store.RestoreTrashedTask(id); err != nil {
+		writeError(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
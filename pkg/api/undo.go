@@ -0,0 +1,151 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/Evrard-ro/final_project/pkg/db"
+)
+
+const (
+	// UndoTokenHeader — заголовок ответа, в котором возвращается токен отмены.
+	// Тело ответа остаётся прежним, поэтому клиенты без поддержки отмены не меняются.
+	UndoTokenHeader = "X-Undo-Token"
+
+	DefaultUndoWindow = 5 * time.Minute
+)
+
+// undoEntry — состояние задачи до разрушительной операции
+type undoEntry struct {
+	task *db.Task
+	// completionID — запись истории, созданная операцией (0, если её нет)
+	completionID int64
+	expires      time.Time
+}
+
+var undoWindow = DefaultUndoWindow
+
+// undoStore хранит в памяти состояния задач для отмены до истечения окна
+type undoStore struct {
+	mu      sync.Mutex
+	entries map[string]*undoEntry
+}
+
+// InitUndo читает окно отмены из TODO_UNDO_WINDOW (например, 10m или 1h)
+func InitUndo() error {
+	s := os.Getenv("TODO_UNDO_WINDOW")
+	if s == "" {
+		undoWindow = DefaultUndoWindow
+		return nil
+	}
+
+	window, err := time.ParseDuration(s)
+	if err != nil || window <= 0 {
+		return fmt.Errorf("invalid TODO_UNDO_WINDOW %q", s)
+	}
+	undoWindow = window
+	return nil
+}
+
+// saveUndo запоминает состояние задачи и возвращает токен для его восстановления
+func (a *API) saveUndo(task *db.Task, completionID int64) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	a.undo.mu.Lock()
+	defer a.undo.mu.Unlock()
+
+	// Заодно убираем просроченные записи
+	now := time.Now()
+	for t, e := range a.undo.entries {
+		if now.After(e.expires) {
+			delete(a.undo.entries, t)
+		}
+	}
+
+	a.undo.entries[token] = &undoEntry{
+		task:         task,
+		completionID: completionID,
+		expires:      now.Add(undoWindow),
+	}
+	return token, nil
+}
+
+// takeUndo извлекает запись по токену; каждую операцию можно отменить только один раз
+func (a *API) takeUndo(token string) (*undoEntry, bool) {
+	a.undo.mu.Lock()
+	defer a.undo.mu.Unlock()
+
+	e, ok := a.undo.entries[token]
+	if !ok {
+		return nil, false
+	}
+	delete(a.undo.entries, token)
+	if time.Now().After(e.expires) {
+		return nil, false
+	}
+	return e, true
+}
+
+// returnUndo возвращает запись, извлечённую takeUndo, если отменить операцию не удалось
+func (a *API) returnUndo(token string, e *undoEntry) {
+	a.undo.mu.Lock()
+	defer a.undo.mu.Unlock()
+	a.undo.entries[token] = e
+}
+
+// setUndoToken сохраняет состояние задачи и передаёт токен отмены в заголовке ответа
+func (a *API) setUndoToken(w http.ResponseWriter, task *db.Task, completionID int64) error {
+	token, err := a.saveUndo(task, completionID)
+	if err != nil {
+		return err
+	}
+	w.Header().Set(UndoTokenHeader, token)
+	return nil
+}
+
+// undoHandler обрабатывает POST /api/undo?token= и восстанавливает задачу
+func (a *API) undoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.FormValue("token")
+	if token == "" {
+		writeError(w, "Не указан токен отмены", http.StatusBadRequest)
+		return
+	}
+
+	entry, ok := a.takeUndo(token)
+	if !ok {
+		writeError(w, "Действие нельзя отменить: токен неизвестен или истёк", http.StatusNotFound)
+		return
+	}
+
+	// Задача и история восстанавливаются вместе; при ошибке токен снова можно использовать
+	err := a.store.InTx(func(tx db.TaskStore) error {
+		if err := tx.RestoreTask(entry.task); err != nil {
+			return err
+		}
+		if entry.completionID > 0 {
+			return tx.DeleteCompletion(entry.completionID)
+		}
+		return nil
+	})
+	if err != nil {
+		a.returnUndo(token, entry)
+		writeError(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, map[string]string{}, http.StatusOK)
+}
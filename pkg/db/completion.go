@@ -0,0 +1,114 @@
+package db
+
+import "strconv"
+
+// Completion — запись о выполнении задачи
+type Completion struct {
+	ID          string `json:"id"`
+	TaskID      string `json:"task_id"`
+	Title       string `json:"title"`
+	Date        string `json:"date"`
+	CompletedAt string `json:"completed_at"`
+}
+
+// AddCompletion сохраняет запись о выполнении задачи
+func (s *SQLStore) AddCompletion(c *Completion) (int64, error) {
+	var id int64
+	query := `INSERT INTO task_completions (task_id, title, date, completed_at) VALUES (?, ?, ?, ?) RETURNING id`
+	err := s.queryRow(query, c.TaskID, c.Title, c.Date, c.CompletedAt).Scan(&id)
+	return id, err
+}
+
+// CompletionsOptions — параметры выборки истории выполнений
+type CompletionsOptions struct {
+	// Limit — размер страницы
+	Limit int
+	// From и To — границы времени выполнения [From, To) в RFC 3339; пустая граница не ограничивает
+	From, To string
+	// TaskID — выполнения одной задачи; пусто — всех задач
+	TaskID string
+	// Cursor — курсор страницы из CompletionPage.NextCursor; пусто для первой страницы
+	Cursor string
+}
+
+// CompletionPage — страница истории выполнений
+type CompletionPage struct {
+	Completions []*Completion
+	// NextCursor — курсор следующей страницы; пусто, если выполнений больше нет
+	NextCursor string
+}
+
+// completionsList — имя истории выполнений в курсоре страницы
+const completionsList = "completions"
+
+// Completions возвращает страницу выполнений начиная с последних
+func (s *SQLStore) Completions(opts CompletionsOptions) (*CompletionPage, error) {
+	cursor, err := decodeNewestCursor(opts.Cursor, completionsList)
+	if err != nil {
+		return nil, err
+	}
+
+	where, args := conditions{}, []any{}
+	if opts.From != "" {
+		where = append(where, "completed_at >= ?")
+		args = append(args, opts.From)
+	}
+	if opts.To != "" {
+		where = append(where, "completed_at < ?")
+		args = append(args, opts.To)
+	}
+	if opts.TaskID != "" {
+		where = append(where, "task_id = ?")
+		args = append(args, opts.TaskID)
+	}
+	if cursor != nil {
+		cond, condArgs := cursor.olderCondition("completed_at")
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	// Лишняя запись показывает, есть ли следующая страница
+	query := `SELECT id, task_id, title, date, completed_at FROM task_completions` + where.sql() +
+		` ORDER BY completed_at DESC, id DESC LIMIT ?`
+	rows, err := s.query(query, append(args, opts.Limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	completions := make([]*Completion, 0)
+	for rows.Next() {
+		var c Completion
+		var id, taskIDInt int64
+		if err := rows.Scan(&id, &taskIDInt, &c.Title, &c.Date, &c.CompletedAt); err != nil {
+			return nil, err
+		}
+		c.ID = strconv.FormatInt(id, 10)
+		c.TaskID = strconv.FormatInt(taskIDInt, 10)
+		completions = append(completions, &c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return completionPage(completions, opts.Limit), nil
+}
+
+// completionPage обрезает выборку до limit записей и выдаёт курсор, если записей было больше
+func completionPage(completions []*Completion, limit int) *CompletionPage {
+	page := &CompletionPage{Completions: completions}
+	if len(completions) > limit {
+		page.Completions = completions[:limit]
+		last := page.Completions[limit-1]
+		page.NextCursor = newestCursor(completionsList, last.CompletedAt, parseID(last.ID))
+	}
+	return page
+}
+
+// DeleteCompletion удаляет запись о выполнении, например при отмене действия
+func (s *SQLStore) DeleteCompletion(id int64) error {
+	query := `DELETE FROM task_completions WHERE id = ?`
+	_, err := s.exec(query, id)
+	return err
+}
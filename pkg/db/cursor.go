@@ -0,0 +1,123 @@
+package db
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+// ErrBadCursor — курсор страницы повреждён или получен для другого запроса
+var ErrBadCursor = errors.New("invalid cursor")
+
+// TaskPage — страница списка задач
+type TaskPage struct {
+	Tasks []*Task
+	// NextCursor — курсор следующей страницы; пусто, если задач больше нет
+	NextCursor string
+	// Total — число задач по запросу без учёта страниц; считается при TasksOptions.Total
+	Total int
+}
+
+// taskCursor — место, с которого продолжается выборка. Для списка в порядке сортировки это
+// значения ключей и id последней задачи: новые задачи не сдвигают уже показанные страницы.
+// Для поиска по релевантности порядок зависит от всего набора задач, поэтому там — смещение.
+type taskCursor struct {
+	// Sort — порядок сортировки, для которого выдан курсор
+	Sort   string   `json:"s,omitempty"`
+	Keys   []string `json:"k,omitempty"`
+	ID     int64    `json:"i,omitempty"`
+	Offset int      `json:"o,omitempty"`
+}
+
+func (c *taskCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor разбирает курсор для порядка sort из keys; ranked — выборка по релевантности.
+// Пустая строка — первая страница (nil).
+func decodeCursor(s string, sort string, keys []sortKey, ranked bool) (*taskCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	var c taskCursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, ErrBadCursor
+	}
+	if ranked {
+		if c.ID != 0 || c.Keys != nil || c.Offset < 0 {
+			return nil, ErrBadCursor
+		}
+		return &c, nil
+	}
+
+	if c.ID == 0 || c.Sort != sort || len(c.Keys) != len(keys) {
+		return nil, ErrBadCursor
+	}
+	for i, k := range keys {
+		if _, err := strconv.Atoi(c.Keys[i]); sortColumns[k.column].numeric && err != nil {
+			return nil, ErrBadCursor
+		}
+	}
+	return &c, nil
+}
+
+// after возвращает true, если задача идёт в порядке keys после курсора
+func (c *taskCursor) after(keys []sortKey, task *Task) bool {
+	cursor := &Task{ID: strconv.FormatInt(c.ID, 10)}
+	for i, k := range keys {
+		sortColumns[k.column].set(cursor, c.Keys[i])
+	}
+	return compareTasks(keys, task, cursor) > 0
+}
+
+// nextCursor возвращает курсор страницы, следующей за tasks
+func nextCursor(tasks []*Task, sort string, keys []sortKey, ranked bool, offset int) string {
+	if ranked {
+		return (&taskCursor{Offset: offset + len(tasks)}).encode()
+	}
+	last := tasks[len(tasks)-1]
+	c := &taskCursor{Sort: sort, Keys: make([]string, 0, len(keys)), ID: taskID(last)}
+	for _, k := range keys {
+		c.Keys = append(c.Keys, sortColumns[k.column].get(last))
+	}
+	return c.encode()
+}
+
+// decodeNewestCursor разбирает курсор списка list, упорядоченного от новых записей к старым.
+// В Keys курсора — время последней записи. Пустая строка — первая страница (nil).
+func decodeNewestCursor(s, list string) (*taskCursor, error) {
+	if s == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+	var c taskCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != list || c.ID == 0 || len(c.Keys) != 1 {
+		return nil, ErrBadCursor
+	}
+	return &c, nil
+}
+
+// newestCursor возвращает курсор списка list после записи со временем at и идентификатором id
+func newestCursor(list, at string, id int64) string {
+	return (&taskCursor{Sort: list, Keys: []string{at}, ID: id}).encode()
+}
+
+// olderCondition возвращает условие WHERE для записей, которые идут после курсора
+// в порядке column DESC, id DESC
+func (c *taskCursor) olderCondition(column string) (string, []any) {
+	return "(" + column + " < ? OR (" + column + " = ? AND id < ?))", []any{c.Keys[0], c.Keys[0], c.ID}
+}
+
+// older возвращает true, если запись со временем at и идентификатором id идёт после курсора
+func (c *taskCursor) older(at string, id int64) bool {
+	return at < c.Keys[0] || (at == c.Keys[0] && id < c.ID)
+}
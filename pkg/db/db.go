@@ -2,55 +2,118 @@ package db
 
 import (
 	"database/sql"
-	"os"
+	"fmt"
+	"strings"
+
 	_ "modernc.org/sqlite"
 )
 
-var DB *sql.DB
-
-const schema = `
-CREATE TABLE IF NOT EXISTS scheduler (
-    id INTEGER PRIMARY KEY AUTOINCREMENT,
-    date CHAR(8) NOT NULL DEFAULT '',
-    title VARCHAR(255) NOT NULL DEFAULT '',
-    comment TEXT NOT NULL DEFAULT '',
-    repeat VARCHAR(128) NOT NULL DEFAULT ''
-);
-CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler(date);
-`
-
-func Init(dbFile string) error {
-	_, err := os.Stat(dbFile)
-	install := false
-	if err != nil {
-		install = true
+// SQLStore — хранилище задач в базе данных SQL
+type SQLStore struct {
+	db *sql.DB
+	// conn — база или открытая транзакция, через которую выполняются запросы
+	conn    sqlConn
+	dialect *dialect
+	// savepoints — глубина вложенных транзакций внутри conn
+	savepoints int
+}
+
+// sqlConn — общие методы *sql.DB и *sql.Tx
+type sqlConn interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// sqliteBusyTimeout — сколько миллисекунд запрос ждёт, пока база заблокирована другим соединением
+const sqliteBusyTimeout = 5000
+
+// OpenSQLite открывает файл базы SQLite и приводит её схему к последней версии
+func OpenSQLite(dbFile string) (*SQLStore, error) {
+	// Запись ждёт, пока другое соединение закончит транзакцию, вместо ошибки SQLITE_BUSY.
+	// Транзакция сразу берёт блокировку на запись: повысить блокировку чтения посреди
+	// транзакции SQLite не даёт без ожидания, если другое соединение уже пишет.
+	sep := "?"
+	if strings.Contains(dbFile, "?") {
+		sep = "&"
 	}
-	
-	database, err := sql.Open("sqlite", dbFile)
+	dsn := fmt.Sprintf("%s%s_pragma=busy_timeout(%d)&_txlock=immediate", dbFile, sep, sqliteBusyTimeout)
+	return open("sqlite", dsn, sqliteDialect)
+}
+
+func open(driver, dsn string, d *dialect) (*SQLStore, error) {
+	database, err := sql.Open(driver, dsn)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if err = migrate(database, d); err != nil {
+		database.Close()
+		return nil, err
 	}
-	
-	if install {
-		_, err = database.Exec(schema)
-		if err != nil {
+
+	return &SQLStore{db: database, conn: database, dialect: d}, nil
+}
+
+// inTx выполняет fn в транзакции: при ошибке все изменения fn отменяются.
+// Внутри уже открытой транзакции fn выполняется в ней же, а при ошибке
+// откатываются только изменения fn — до точки сохранения.
+func (s *SQLStore) inTx(fn func(tx *SQLStore) error) error {
+	if _, ok := s.conn.(*sql.Tx); ok {
+		nested := &SQLStore{db: s.db, conn: s.conn, dialect: s.dialect, savepoints: s.savepoints + 1}
+		name := fmt.Sprintf("sp%d", nested.savepoints)
+		if _, err := s.conn.Exec("SAVEPOINT " + name); err != nil {
+			return err
+		}
+		if err := fn(nested); err != nil {
+			s.conn.Exec("ROLLBACK TO SAVEPOINT " + name)
 			return err
 		}
+		_, err := s.conn.Exec("RELEASE SAVEPOINT " + name)
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
 	}
-	
-	DB = database
-	return nil
+	if err := fn(&SQLStore{db: s.db, conn: tx, dialect: s.dialect}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
 }
 
-// GetDB возвращает указатель на объект базы данных
-func GetDB() *sql.DB {
-	return DB
+// InTx выполняет fn в одной транзакции с хранилищем tx; при ошибке fn все её изменения отменяются
+func (s *SQLStore) InTx(fn func(tx TaskStore) error) error {
+	return s.inTx(func(tx *SQLStore) error {
+		return fn(tx)
+	})
 }
 
 // Close закрывает соединение с базой данных
-func Close() error {
-	if DB != nil {
-		return DB.Close()
+func (s *SQLStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *SQLStore) exec(query string, args ...any) (sql.Result, error) {
+	return s.conn.Exec(s.dialect.rebind(query), args...)
+}
+
+func (s *SQLStore) query(query string, args ...any) (*sql.Rows, error) {
+	return s.conn.Query(s.dialect.rebind(query), args...)
+}
+
+func (s *SQLStore) queryRow(query string, args ...any) *sql.Row {
+	return s.conn.QueryRow(s.dialect.rebind(query), args...)
+}
+
+// conditions — условия WHERE, объединяемые через AND
+type conditions []string
+
+func (c conditions) sql() string {
+	if len(c) == 0 {
+		return ""
 	}
-	return nil
-}
\ No newline at end of file
+	return " WHERE " + strings.Join(c, " AND ")
+}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// ErrDependencyCycle — задача оказалась бы заблокирована сама собой
+var ErrDependencyCycle = errors.New("task dependency cycle")
+
+// openBlocker — условие SQL для задачи-блокера blocker, которая ещё не выполнена.
+// Повторяющаяся задача не получает done = 1, поэтому для связи task_deps она считается
+// выполненной, если её выполнение записано не раньше появления связи.
+const openBlocker = `blocker.done = 0 AND blocker.deleted_at = '' AND NOT (blocker.repeat != '' AND EXISTS (
+	SELECT 1 FROM task_completions
+	WHERE task_completions.task_id = blocker.id AND task_completions.completed_at >= task_deps.created_at))`
+
+// otherIDs возвращает условие SQL «column не из ids»; пустой список ничего не исключает
+func otherIDs(column string, ids []string) (string, []any) {
+	if len(ids) == 0 {
+		return "1 = 1", nil
+	}
+	args := make([]any, 0, len(ids))
+	for _, id := range ids {
+		args = append(args, parseID(id))
+	}
+	return column + " NOT IN (?" + strings.Repeat(", ?", len(ids)-1) + ")", args
+}
+
+// setTaskDeps заменяет задачи, от которых зависит задача taskID, и проверяет, что зависимости не образуют цикл.
+// Оставшиеся связи сохраняют время появления, новые получают текущее.
+func (s *SQLStore) setTaskDeps(taskID int64, blockers []string) error {
+	cond, args := otherIDs("blocker_id", blockers)
+	if _, err := s.exec(`DELETE FROM task_deps WHERE task_id = ? AND `+cond, append([]any{taskID}, args...)...); err != nil {
+		return err
+	}
+
+	created := now()
+	for _, blocker := range blockers {
+		if parseID(blocker) == taskID {
+			return ErrDependencyCycle
+		}
+		query := `INSERT INTO task_deps (task_id, blocker_id, created_at) VALUES (?, ?, ?) ON CONFLICT DO NOTHING`
+		if _, err := s.exec(query, taskID, parseID(blocker), created); err != nil {
+			return err
+		}
+	}
+	if len(blockers) == 0 {
+		return nil
+	}
+
+	// Цикл есть, если по цепочке блокеров можно вернуться к самой задаче
+	var count int
+	query := `WITH RECURSIVE chain(id) AS (
+			SELECT blocker_id FROM task_deps WHERE task_id = ?
+			UNION
+			SELECT task_deps.blocker_id FROM task_deps JOIN chain ON task_deps.task_id = chain.id
+		)
+		SELECT COUNT(*) FROM chain WHERE id = ?`
+	if err := s.queryRow(query, taskID, taskID).Scan(&count); err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDependencyCycle
+	}
+	return nil
+}
+
+// restoreTaskDeps возвращает задаче прежние связи в обе стороны без проверки цикла:
+// они были допустимы, когда задачу запомнили. Связи с уже удалёнными задачами пропускаются,
+// уцелевшие связи сохраняют время появления.
+func (s *SQLStore) restoreTaskDeps(task *Task) error {
+	id := taskID(task)
+	blockers, blockerArgs := otherIDs("blocker_id", task.DependsOn)
+	dependents, dependentArgs := otherIDs("task_id", task.Blocks)
+	args := append(append([]any{id}, blockerArgs...), id)
+	query := `DELETE FROM task_deps WHERE (task_id = ? AND ` + blockers + `) OR (blocker_id = ? AND ` + dependents + `)`
+	if _, err := s.exec(query, append(args, dependentArgs...)...); err != nil {
+		return err
+	}
+
+	created := now()
+	for _, blocker := range task.DependsOn {
+		query := `INSERT INTO task_deps (task_id, blocker_id, created_at)
+			SELECT CAST(? AS INTEGER), id, ? FROM scheduler WHERE id = ? ON CONFLICT DO NOTHING`
+		if _, err := s.exec(query, id, created, parseID(blocker)); err != nil {
+			return err
+		}
+	}
+	for _, dependent := range task.Blocks {
+		query := `INSERT INTO task_deps (task_id, blocker_id, created_at)
+			SELECT id, CAST(? AS INTEGER), ? FROM scheduler WHERE id = ? ON CONFLICT DO NOTHING`
+		if _, err := s.exec(query, id, created, parseID(dependent)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadDeps заполняет зависимости задач и их невыполненные блокеры одним запросом
+func (s *SQLStore) loadDeps(tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Task, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, task := range tasks {
+		byID[taskID(task)] = task
+		args = append(args, taskID(task))
+	}
+
+	query := `SELECT task_deps.task_id, task_deps.blocker_id, CASE WHEN ` + openBlocker + ` THEN 1 ELSE 0 END
+		FROM task_deps JOIN scheduler blocker ON blocker.id = task_deps.blocker_id
+		WHERE task_deps.task_id IN (?` + strings.Repeat(", ?", len(args)-1) + `) ORDER BY task_deps.blocker_id`
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, blocker int64
+		var open bool
+		if err := rows.Scan(&id, &blocker, &open); err != nil {
+			return err
+		}
+		task, ok := byID[id]
+		if !ok {
+			continue
+		}
+		task.DependsOn = append(task.DependsOn, strconv.FormatInt(blocker, 10))
+		if open {
+			task.BlockedBy = append(task.BlockedBy, strconv.FormatInt(blocker, 10))
+		}
+	}
+	return rows.Err()
+}
+
+// loadBlocks заполняет задачи, которые зависят от задачи task
+func (s *SQLStore) loadBlocks(task *Task) error {
+	rows, err := s.query(`SELECT task_id FROM task_deps WHERE blocker_id = ? ORDER BY task_id`, taskID(task))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		task.Blocks = append(task.Blocks, strconv.FormatInt(id, 10))
+	}
+	return rows.Err()
+}
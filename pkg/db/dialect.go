@@ -0,0 +1,109 @@
+package db
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dialect описывает различия SQL между поддерживаемыми базами данных.
+// Запросы пишутся в синтаксисе SQLite с параметрами ?, а dialect приводит их к нужному виду.
+type dialect struct {
+	// numbered — параметры записываются как $1, $2, … вместо ?
+	numbered bool
+	// searchFrom — источник строк для полнотекстового поиска с параметром запроса,
+	// searchWhere — дополнительное условие отбора, searchSnippet — фрагмент текста
+	// с совпадениями, searchRank — порядок от самых подходящих задач
+	searchFrom, searchWhere, searchSnippet, searchRank string
+	// searchExpr переводит разобранный запрос в синтаксис полнотекстового поиска базы
+	searchExpr func(n *searchNode) string
+	// matchColumn возвращает условие с параметром и его значение для поиска слов
+	// в одном столбце задачи или, если column пуст, в заголовке и комментарии
+	matchColumn func(column string, n *searchNode) (string, string)
+	// ddl переводит описание таблиц в синтаксис базы
+	ddl func(script string) string
+	// columnsQuery возвращает имена столбцов таблицы, переданной параметром
+	columnsQuery string
+	// migrationLock не даёт нескольким экземплярам приложения менять схему одновременно
+	migrationLock string
+}
+
+var sqliteDialect = &dialect{
+	ddl:          func(script string) string { return script },
+	columnsQuery: `SELECT name FROM pragma_table_info(?)`,
+	searchFrom: `scheduler JOIN (SELECT rowid, bm25(scheduler_fts, 10.0, 1.0) AS rank,
+			snippet(scheduler_fts, -1, '` + snippetOpen + `', '` + snippetClose + `', '…', 12) AS snippet
+		FROM scheduler_fts WHERE scheduler_fts MATCH ?) AS fts ON fts.rowid = scheduler.id`,
+	searchSnippet: `fts.snippet`,
+	// Совпадения в заголовке весят больше, чем в комментарии
+	searchRank: `fts.rank`,
+	searchExpr: (*searchNode).fts5,
+	matchColumn: func(column string, n *searchNode) (string, string) {
+		expr := n.fts5()
+		if column != "" {
+			expr = column + " : " + expr
+		}
+		return `id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?)`, expr
+	},
+}
+
+var postgresDialect = &dialect{
+	numbered: true,
+	// CHAR в Postgres дополняется пробелами, поэтому строки хранятся как VARCHAR
+	ddl: strings.NewReplacer(
+		"INTEGER PRIMARY KEY AUTOINCREMENT", "BIGSERIAL PRIMARY KEY",
+		" CHAR(", " VARCHAR(",
+	).Replace,
+	columnsQuery: `SELECT column_name FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name = ?`,
+	migrationLock: `SELECT pg_advisory_xact_lock(7540)`,
+	searchFrom:    `scheduler, to_tsquery('simple', ?) AS q`,
+	searchWhere:   postgresSearchVector + ` @@ q`,
+	searchSnippet: `ts_headline('simple', title || ' ' || comment, q,
+		'StartSel=` + snippetOpen + `, StopSel=` + snippetClose + `, MaxWords=12, MinWords=4')`,
+	searchRank: `ts_rank(setweight(to_tsvector('simple', title), 'A') ||
+		setweight(to_tsvector('simple', comment), 'B'), q) DESC`,
+	searchExpr: (*searchNode).tsquery,
+	matchColumn: func(column string, n *searchNode) (string, string) {
+		vector := postgresSearchVector
+		if column != "" {
+			vector = `to_tsvector('simple', ` + column + `)`
+		}
+		return vector + ` @@ to_tsquery('simple', ?)`, n.tsquery()
+	},
+}
+
+// postgresSearchVector — выражение, по которому построен индекс полнотекстового поиска в PostgreSQL
+const postgresSearchVector = `to_tsvector('simple', title || ' ' || comment)`
+
+// rebind заменяет параметры ? на нумерованные, если база их требует.
+// Знаки вопроса внутри строковых литералов не меняются.
+func (d *dialect) rebind(query string) string {
+	if !d.numbered {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	quoted := false
+	for _, r := range query {
+		switch {
+		case r == '\'':
+			quoted = !quoted
+		case r == '?' && !quoted:
+			n++
+			b.WriteByte('$')
+			b.WriteString(strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// boolInt переводит флаг в 0 или 1 для столбцов INTEGER
+func boolInt(v bool) int {
+	if v {
+		return 1
+	}
+	return 0
+}
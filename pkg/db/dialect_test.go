@@ -0,0 +1,413 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebind(t *testing.T) {
+	query := `SELECT id FROM scheduler WHERE title = ? AND comment != '?' AND date >= ? LIMIT ?`
+	assert.Equal(t, query, sqliteDialect.rebind(query))
+	assert.Equal(t, `SELECT id FROM scheduler WHERE title = $1 AND comment != '?' AND date >= $2 LIMIT $3`,
+		postgresDialect.rebind(query))
+}
+
+func TestPostgresDDL(t *testing.T) {
+	assert.Equal(t, `id BIGSERIAL PRIMARY KEY, date VARCHAR(8), title VARCHAR(255)`,
+		postgresDialect.ddl(`id INTEGER PRIMARY KEY AUTOINCREMENT, date CHAR(8), title VARCHAR(255)`))
+}
+
+// recorder — подключение database/sql, которое запоминает запросы вместо выполнения.
+// На SELECT и RETURNING оно отвечает одной строкой из единиц нужной ширины,
+// чтобы методы хранилища дошли до следующих запросов.
+type recorder struct {
+	mu      sync.Mutex
+	queries []recorded
+}
+
+type recorded struct {
+	query string
+	args  []driver.Value
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return &recorderConn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+// find возвращает записанные запросы, в которых без учёта отступов есть part
+func (r *recorder) find(part string) []recorded {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var found []recorded
+	for _, q := range r.queries {
+		if strings.Contains(squash(q.query), part) {
+			found = append(found, q)
+		}
+	}
+	return found
+}
+
+type recorderConn struct {
+	r *recorder
+}
+
+func (c *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return &recorderStmt{r: c.r, query: query}, nil
+}
+func (c *recorderConn) Close() error              { return nil }
+func (c *recorderConn) Begin() (driver.Tx, error) { return recorderTx{}, nil }
+
+type recorderTx struct{}
+
+func (recorderTx) Commit() error   { return nil }
+func (recorderTx) Rollback() error { return nil }
+
+type recorderStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s *recorderStmt) Close() error  { return nil }
+func (s *recorderStmt) NumInput() int { return -1 }
+
+func (s *recorderStmt) record(args []driver.Value) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+	s.r.queries = append(s.r.queries, recorded{query: s.query, args: args})
+}
+
+func (s *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.record(args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.record(args)
+	width := resultWidth(s.query)
+	row := make([]driver.Value, width)
+	for i := range row {
+		row[i] = int64(1)
+	}
+	switch query := squash(s.query); {
+	case strings.HasPrefix(query, "SELECT COALESCE(MAX(version), 0)"):
+		// Пустая база: применяются все миграции
+		row[0] = int64(0)
+	case strings.HasPrefix(query, "SELECT COUNT(*) FROM task_items"):
+		// Больше одного пункта, чтобы UpdateTaskItem дошёл до сдвига позиций
+		row[0] = int64(3)
+	}
+	return &recorderRows{width: width, row: row}, nil
+}
+
+type recorderRows struct {
+	width int
+	row   []driver.Value
+	done  bool
+}
+
+func (r *recorderRows) Columns() []string { return make([]string, r.width) }
+func (r *recorderRows) Close() error      { return nil }
+
+func (r *recorderRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.row)
+	return nil
+}
+
+// resultWidth возвращает число столбцов результата запроса: после RETURNING
+// или в списке внешнего SELECT до FROM
+func resultWidth(query string) int {
+	if i := strings.LastIndex(query, "RETURNING "); i >= 0 {
+		return topLevelCommas(query[i:], "") + 1
+	}
+	depth, quoted := 0, false
+	for i := 0; i < len(query); i++ {
+		switch c := query[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && strings.HasPrefix(query[i:], "SELECT "):
+			return topLevelCommas(query[i:], " FROM ") + 1
+		}
+	}
+	return 0
+}
+
+// topLevelCommas считает запятые вне скобок и строк до первого stop вне скобок
+func topLevelCommas(s, stop string) int {
+	depth, quoted, count := 0, false, 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && c == ',':
+			count++
+		case depth == 0 && stop != "" && strings.HasPrefix(s[i:], stop):
+			return count
+		}
+	}
+	return count
+}
+
+// squash заменяет переводы строк и отступы одним пробелом
+func squash(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// unquoted возвращает запрос без строковых литералов
+func unquoted(query string) string {
+	var b strings.Builder
+	quoted := false
+	for _, r := range query {
+		if r == '\'' {
+			quoted = !quoted
+			continue
+		}
+		if !quoted {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// sqliteOnly — конструкции SQLite, которых не должно быть в запросах к PostgreSQL
+var sqliteOnly = []string{
+	"AUTOINCREMENT", " CHAR(", "VIRTUAL TABLE", "TRIGGER", "scheduler_fts", "MATCH",
+	"bm25(", "snippet(", "rowid", "pragma", "INSERT OR ",
+}
+
+// openRecorder открывает хранилище с диалектом PostgreSQL поверх recorder и применяет миграции
+func openRecorder(t *testing.T) (*SQLStore, *recorder) {
+	r := &recorder{}
+	database := sql.OpenDB(r)
+	t.Cleanup(func() { database.Close() })
+	if !assert.NoError(t, migrate(database, postgresDialect)) {
+		t.FailNow()
+	}
+	return &SQLStore{db: database, conn: database, dialect: postgresDialect}, r
+}
+
+// Каждый запрос, который хранилище отправляет в PostgreSQL, — от миграций до
+// методов TaskStore — записан с нумерованными параметрами и без синтаксиса SQLite
+func TestPostgresStatements(t *testing.T) {
+	store, r := openRecorder(t)
+
+	// Ошибки не важны: методы получают вымышленные ответы, проверяются только запросы
+	done := true
+	task := &Task{ID: "1", Date: "20300101", Title: "Задача", Tags: []string{"дом"}, DependsOn: []string{"2"},
+		Items: []*TaskItem{{Title: "Пункт"}, {ID: "5", Title: "Пункт"}}, Blocks: []string{"3"}}
+	store.AddTask(task)
+	store.RestoreTask(task)
+	store.GetTask("1")
+	store.UpdateTask(task)
+	store.UpdateTaskDate("1", "20300102", 2)
+	store.MarkTaskDone("1")
+	store.DeleteTask("1")
+	store.Tasks(TasksOptions{Limit: 10, Total: true})
+	store.Tasks(TasksOptions{Limit: 10, Search: "сыр -молоко title:отчёт comment:вода tag:дом priority:>=2 before:20301231",
+		From: "20300101", To: "20301231", Tags: []string{"дом"}, Priorities: []int{1, 2}, ProjectID: "1", Blocked: &done})
+	store.Tasks(TasksOptions{Limit: 10, Sort: "-priority,title", Blocked: new(bool)})
+	store.TrashedTasks(10, newestCursor(trashList, "2030-01-01T00:00:00Z", 5))
+	store.RestoreTrashedTask("1")
+	store.PurgeTask("1")
+	store.PurgeTrashedTask("1")
+	store.PurgeTrash(time.Now())
+	store.EmptyTrash()
+	store.AddTaskItem(&TaskItem{TaskID: "1", Title: "Пункт"})
+	store.GetTaskItem("1")
+	store.UpdateTaskItem(&TaskItem{ID: "1", Title: "Пункт", Position: 3})
+	store.DeleteTaskItem("1")
+	store.TaskItems("1")
+	store.ResetTaskItems("1")
+	store.Tags()
+	store.MergeTags([]string{"дом", "семья"}, "быт")
+	store.AddProject(&Project{Name: "Дом"})
+	store.GetProject("1")
+	store.UpdateProject(&Project{ID: "1", Name: "Дом"})
+	store.DeleteProject("1", "2", false)
+	store.DeleteProject("1", "", true)
+	store.Projects(true)
+	store.AddHoliday(&Holiday{Date: "20300101"})
+	store.DeleteHoliday("20300101")
+	store.Holidays("20300101", "20301231")
+	store.HolidaySet()
+	store.AddCompletion(&Completion{TaskID: "1", Date: "20300101"})
+	store.DeleteCompletion(1)
+	store.Completions(CompletionsOptions{Limit: 10, From: "2030-01-01T00:00:00Z", To: "2031-01-01T00:00:00Z", TaskID: "1",
+		Cursor: newestCursor(completionsList, "2030-06-01T00:00:00Z", 5)})
+	store.InTx(func(tx TaskStore) error {
+		return tx.InTx(func(TaskStore) error { return ErrDependencyCycle })
+	})
+	store.SchemaVersion()
+
+	r.mu.Lock()
+	queries := r.queries
+	r.mu.Unlock()
+	assert.Greater(t, len(queries), 100)
+	for _, q := range queries {
+		text := unquoted(q.query)
+		assert.NotContains(t, text, "?", q.query)
+		for _, s := range sqliteOnly {
+			assert.NotContains(t, q.query, s, q.query)
+		}
+
+		// Параметры идут подряд с $1, и их столько же, сколько аргументов
+		used := make(map[int]bool)
+		for _, m := range placeholder.FindAllStringSubmatch(text, -1) {
+			n, _ := strconv.Atoi(m[1])
+			used[n] = true
+		}
+		for n := 1; n <= len(used); n++ {
+			assert.True(t, used[n], "$%d: %s", n, q.query)
+		}
+		assert.Len(t, q.args, len(used), q.query)
+	}
+}
+
+// Таблицы описываются в синтаксисе SQLite и переводятся для PostgreSQL
+func TestPostgresMigrations(t *testing.T) {
+	_, r := openRecorder(t)
+
+	assert.Len(t, r.find("SELECT pg_advisory_xact_lock(7540)"), 1)
+	assert.Equal(t, []string{
+		`CREATE TABLE IF NOT EXISTS scheduler ( id BIGSERIAL PRIMARY KEY, date VARCHAR(8) NOT NULL DEFAULT '', ` +
+			`title VARCHAR(255) NOT NULL DEFAULT '', comment TEXT NOT NULL DEFAULT '', repeat VARCHAR(128) NOT NULL DEFAULT '' ); ` +
+			`CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler(date);`,
+	}, texts(r.find("CREATE TABLE IF NOT EXISTS scheduler ")))
+	assert.Equal(t, []string{`ALTER TABLE scheduler ADD COLUMN time VARCHAR(5) NOT NULL DEFAULT ''`},
+		texts(r.find("ALTER TABLE scheduler ADD COLUMN time ")))
+	assert.Equal(t, []string{
+		`CREATE INDEX IF NOT EXISTS idx_scheduler_search ON scheduler USING GIN (to_tsvector('simple', title || ' ' || comment));`,
+	}, texts(r.find("CREATE INDEX IF NOT EXISTS idx_scheduler_search")))
+
+	// Связи удаляются по ON DELETE CASCADE, без триггеров SQLite
+	for _, table := range []string{"task_tags", "task_items", "task_deps"} {
+		created := r.find("CREATE TABLE IF NOT EXISTS " + table)
+		if assert.Len(t, created, 1, table) {
+			assert.Contains(t, created[0].query, "REFERENCES scheduler(id) ON DELETE CASCADE", table)
+		}
+	}
+
+	// Столбцы таблицы ищутся в текущей схеме, версия записывается с нумерованными параметрами
+	columns := r.find("SELECT column_name FROM information_schema.columns")
+	if assert.NotEmpty(t, columns) {
+		assert.Equal(t, "SELECT column_name FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = $1",
+			squash(columns[0].query))
+		assert.Equal(t, []driver.Value{"scheduler"}, columns[0].args)
+	}
+	versions := r.find("INSERT INTO schema_version")
+	if assert.Len(t, versions, LatestSchemaVersion()) {
+		assert.Equal(t, "INSERT INTO schema_version (version, name, applied_at) VALUES ($1, $2, $3)", versions[0].query)
+	}
+}
+
+// Новые строки получают идентификатор через RETURNING, а не LastInsertId
+func TestPostgresReturning(t *testing.T) {
+	store, r := openRecorder(t)
+
+	store.AddTask(&Task{Date: "20300101", Title: "Задача"})
+	store.AddTaskItem(&TaskItem{TaskID: "1", Title: "Пункт"})
+	store.AddProject(&Project{Name: "Дом"})
+	store.AddCompletion(&Completion{TaskID: "1", Date: "20300101"})
+
+	for query, want := range map[string]string{
+		"INSERT INTO scheduler": "INSERT INTO scheduler (date, title, comment, repeat, time, duration, priority, project_id, remaining, created_at, updated_at) " +
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id",
+		"INSERT INTO task_items":       "INSERT INTO task_items (task_id, title, done, position) VALUES ($1, $2, $3, $4) RETURNING id",
+		"INSERT INTO projects":         "INSERT INTO projects (name, color, archived, created_at) VALUES ($1, $2, $3, $4) RETURNING id",
+		"INSERT INTO task_completions": "INSERT INTO task_completions (task_id, title, date, completed_at) VALUES ($1, $2, $3, $4) RETURNING id",
+	} {
+		found := r.find(query)
+		if assert.Len(t, found, 1, query) {
+			assert.Equal(t, want, squash(found[0].query))
+		}
+	}
+}
+
+// Поиск по словам идёт по tsvector с индексом idx_scheduler_search, фрагмент строит ts_headline
+func TestPostgresSearch(t *testing.T) {
+	store, r := openRecorder(t)
+
+	store.Tasks(TasksOptions{Limit: 10, Search: "сыр -молоко title:отчёт", Total: true})
+
+	vector := `to_tsvector('simple', title || ' ' || comment)`
+	where := ` FROM scheduler, to_tsquery('simple', $1) AS q WHERE done = 0 AND deleted_at = '' ` +
+		`AND project_id NOT IN (SELECT id FROM projects WHERE archived = 1) ` +
+		`AND NOT (` + vector + ` @@ to_tsquery('simple', $2)) ` +
+		`AND to_tsvector('simple', title) @@ to_tsquery('simple', $3) ` +
+		`AND ` + vector + ` @@ q`
+	args := []driver.Value{`('сыр':*)`, `('молоко')`, `('отчёт')`}
+
+	count := r.find("SELECT COUNT(*) FROM scheduler")
+	if assert.Len(t, count, 1) {
+		assert.Equal(t, "SELECT COUNT(*)"+where, squash(count[0].query))
+		assert.Equal(t, args, count[0].args)
+	}
+
+	found := r.find("SELECT " + taskColumns)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, "SELECT "+taskColumns+", ts_headline('simple', title || ' ' || comment, q, "+
+			"'StartSel="+snippetOpen+", StopSel="+snippetClose+", MaxWords=12, MinWords=4')"+where+
+			" ORDER BY ts_rank(setweight(to_tsvector('simple', title), 'A') || setweight(to_tsvector('simple', comment), 'B'), q) DESC, "+
+			"date, priority DESC, time, id LIMIT $4 OFFSET $5", squash(found[0].query))
+		assert.Equal(t, append(args, int64(11), int64(0)), found[0].args)
+	}
+}
+
+// Цикл зависимостей ищется рекурсивным CTE с нумерованными параметрами
+func TestPostgresRecursiveCTE(t *testing.T) {
+	store, r := openRecorder(t)
+
+	store.UpdateTask(&Task{ID: "1", Date: "20300101", Title: "Задача", DependsOn: []string{"2"}})
+
+	found := r.find("WITH RECURSIVE chain(id)")
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, "WITH RECURSIVE chain(id) AS ( SELECT blocker_id FROM task_deps WHERE task_id = $1 UNION "+
+			"SELECT task_deps.blocker_id FROM task_deps JOIN chain ON task_deps.task_id = chain.id ) "+
+			"SELECT COUNT(*) FROM chain WHERE id = $2", squash(found[0].query))
+		assert.Equal(t, []driver.Value{int64(1), int64(1)}, found[0].args)
+	}
+	assert.Equal(t, []recorded{{
+		query: "DELETE FROM task_deps WHERE task_id = $1 AND blocker_id NOT IN ($2)",
+		args:  []driver.Value{int64(1), int64(2)},
+	}}, r.find("DELETE FROM task_deps"))
+	found = r.find("INSERT INTO task_deps")
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, "INSERT INTO task_deps (task_id, blocker_id, created_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING",
+			found[0].query)
+		assert.Equal(t, []driver.Value{int64(1), int64(2)}, found[0].args[:2])
+		assert.NotEmpty(t, found[0].args[2])
+	}
+}
+
+// texts возвращает текст записанных запросов без переводов строк и отступов
+func texts(queries []recorded) []string {
+	var result []string
+	for _, q := range queries {
+		result = append(result, squash(q.query))
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package db
+
+import "fmt"
+
+type Holiday struct {
+	Date  string `json:"date"`
+	Title string `json:"title"`
+}
+
+// AddHoliday добавляет праздничный день или обновляет название существующего
+func (s *SQLStore) AddHoliday(h *Holiday) error {
+	query := `INSERT INTO holidays (date, title) VALUES (?, ?)
+		ON CONFLICT(date) DO UPDATE SET title = excluded.title`
+	_, err := s.exec(query, h.Date, h.Title)
+	return err
+}
+
+func (s *SQLStore) DeleteHoliday(date string) error {
+	query := `DELETE FROM holidays WHERE date = ?`
+	res, err := s.exec(query, date)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("incorrect date for deleting holiday")
+	}
+
+	return nil
+}
+
+// Holidays возвращает праздничные дни в диапазоне [from, to]; пустая граница не ограничивает выборку
+func (s *SQLStore) Holidays(from, to string) ([]*Holiday, error) {
+	holidays := make([]*Holiday, 0)
+
+	where, args := conditions{}, []any{}
+	if from != "" {
+		where = append(where, "date >= ?")
+		args = append(args, from)
+	}
+	if to != "" {
+		where = append(where, "date <= ?")
+		args = append(args, to)
+	}
+
+	query := `SELECT date, title FROM holidays` + where.sql() + ` ORDER BY date`
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var h Holiday
+		if err := rows.Scan(&h.Date, &h.Title); err != nil {
+			return nil, err
+		}
+		holidays = append(holidays, &h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return holidays, nil
+}
+
+// HolidaySet возвращает множество всех праздничных дат
+func (s *SQLStore) HolidaySet() (map[string]bool, error) {
+	holidays, err := s.Holidays("", "")
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool, len(holidays))
+	for _, h := range holidays {
+		set[h.Date] = true
+	}
+	return set, nil
+}
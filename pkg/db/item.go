@@ -0,0 +1,220 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// TaskItem — пункт чек-листа задачи
+type TaskItem struct {
+	ID     string `json:"id"`
+	TaskID string `json:"task_id"`
+	Title  string `json:"title"`
+	Done   bool   `json:"done"`
+	// Position — порядковый номер пункта в чек-листе, начиная с 1
+	Position int `json:"position"`
+}
+
+// Progress — сколько пунктов чек-листа выполнено из общего числа
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// itemColumns — столбцы пункта в порядке, который ожидает scanTaskItem
+const itemColumns = `id, task_id, title, done, position`
+
+func scanTaskItem(row rowScanner) (*TaskItem, error) {
+	var item TaskItem
+	var id, task int64
+	if err := row.Scan(&id, &task, &item.Title, &item.Done, &item.Position); err != nil {
+		return nil, err
+	}
+	item.ID = strconv.FormatInt(id, 10)
+	item.TaskID = strconv.FormatInt(task, 10)
+	return &item, nil
+}
+
+// progress считает выполненные пункты
+func progress(items []*TaskItem) *Progress {
+	if len(items) == 0 {
+		return nil
+	}
+	p := &Progress{Total: len(items)}
+	for _, item := range items {
+		if item.Done {
+			p.Done++
+		}
+	}
+	return p
+}
+
+// AddTaskItem добавляет пункт в конец чек-листа задачи
+func (s *SQLStore) AddTaskItem(item *TaskItem) (int64, error) {
+	var id int64
+	err := s.inTx(func(tx *SQLStore) error {
+		var position int
+		query := `SELECT COALESCE(MAX(position), 0) + 1 FROM task_items WHERE task_id = ?`
+		if err := tx.queryRow(query, item.TaskID).Scan(&position); err != nil {
+			return err
+		}
+		query = `INSERT INTO task_items (task_id, title, done, position) VALUES (?, ?, ?, ?) RETURNING id`
+		return tx.queryRow(query, item.TaskID, item.Title, boolInt(item.Done), position).Scan(&id)
+	})
+	return id, err
+}
+
+func (s *SQLStore) GetTaskItem(id string) (*TaskItem, error) {
+	query := `SELECT ` + itemColumns + ` FROM task_items WHERE id = ?`
+	return scanTaskItem(s.queryRow(query, id))
+}
+
+// UpdateTaskItem изменяет название и отметку пункта. Если Position больше 0, пункт
+// переставляется на эту позицию (не дальше конца списка), а пункты между старой
+// и новой позицией сдвигаются на его место, так что позиции остаются подряд.
+func (s *SQLStore) UpdateTaskItem(item *TaskItem) error {
+	return s.inTx(func(tx *SQLStore) error {
+		stored, err := tx.GetTaskItem(item.ID)
+		if err != nil {
+			return fmt.Errorf("incorrect id for updating task item")
+		}
+
+		position := stored.Position
+		if item.Position > 0 && item.Position != stored.Position {
+			var count int
+			if err := tx.queryRow(`SELECT COUNT(*) FROM task_items WHERE task_id = ?`, stored.TaskID).Scan(&count); err != nil {
+				return err
+			}
+			position = min(item.Position, count)
+		}
+
+		var query string
+		switch {
+		case position > stored.Position:
+			query = `UPDATE task_items SET position = position - 1 WHERE task_id = ? AND position > ? AND position <= ? AND id != ?`
+			_, err = tx.exec(query, stored.TaskID, stored.Position, position, item.ID)
+		case position < stored.Position:
+			query = `UPDATE task_items SET position = position + 1 WHERE task_id = ? AND position >= ? AND position < ? AND id != ?`
+			_, err = tx.exec(query, stored.TaskID, position, stored.Position, item.ID)
+		}
+		if err != nil {
+			return err
+		}
+
+		query = `UPDATE task_items SET title = ?, done = ?, position = ? WHERE id = ?`
+		_, err = tx.exec(query, item.Title, boolInt(item.Done), position, item.ID)
+		return err
+	})
+}
+
+// DeleteTaskItem удаляет пункт и сдвигает следующие за ним на освободившееся место
+func (s *SQLStore) DeleteTaskItem(id string) error {
+	return s.inTx(func(tx *SQLStore) error {
+		stored, err := tx.GetTaskItem(id)
+		if err != nil {
+			return fmt.Errorf("incorrect id for deleting task item")
+		}
+
+		if _, err := tx.exec(`DELETE FROM task_items WHERE id = ?`, id); err != nil {
+			return err
+		}
+
+		query := `UPDATE task_items SET position = position - 1 WHERE task_id = ? AND position > ?`
+		_, err = tx.exec(query, stored.TaskID, stored.Position)
+		return err
+	})
+}
+
+// TaskItems возвращает пункты чек-листа задачи по порядку
+func (s *SQLStore) TaskItems(taskID string) ([]*TaskItem, error) {
+	items := make([]*TaskItem, 0)
+
+	query := `SELECT ` + itemColumns + ` FROM task_items WHERE task_id = ? ORDER BY position, id`
+	rows, err := s.query(query, taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := scanTaskItem(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// ResetTaskItems снимает отметки со всех пунктов чек-листа задачи
+func (s *SQLStore) ResetTaskItems(taskID string) error {
+	_, err := s.exec(`UPDATE task_items SET done = 0 WHERE task_id = ?`, taskID)
+	return err
+}
+
+// setTaskItems заменяет чек-лист задачи пунктами items, сохраняя их идентификаторы, если они заданы
+func (s *SQLStore) setTaskItems(taskID int64, items []*TaskItem) error {
+	if _, err := s.exec(`DELETE FROM task_items WHERE task_id = ?`, taskID); err != nil {
+		return err
+	}
+
+	for i, item := range items {
+		position := item.Position
+		if position == 0 {
+			position = i + 1
+		}
+		if item.ID == "" {
+			query := `INSERT INTO task_items (task_id, title, done, position) VALUES (?, ?, ?, ?)`
+			_, err := s.exec(query, taskID, item.Title, boolInt(item.Done), position)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		query := `INSERT INTO task_items (id, task_id, title, done, position) VALUES (?, ?, ?, ?, ?)`
+		if _, err := s.exec(query, item.ID, taskID, item.Title, boolInt(item.Done), position); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadProgress заполняет ход выполнения чек-листов задач одним запросом
+func (s *SQLStore) loadProgress(tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Task, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, task := range tasks {
+		byID[taskID(task)] = task
+		args = append(args, taskID(task))
+	}
+
+	query := `SELECT task_id, SUM(done), COUNT(*) FROM task_items
+		WHERE task_id IN (?` + strings.Repeat(", ?", len(args)-1) + `) GROUP BY task_id`
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var p Progress
+		if err := rows.Scan(&id, &p.Done, &p.Total); err != nil {
+			return err
+		}
+		if task, ok := byID[id]; ok {
+			task.Progress = &p
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,941 @@
+package db
+
+import (
+	"cmp"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore — хранилище задач в памяти процесса.
+// Повторяет поведение SQLStore и нужно для быстрых тестов обработчиков.
+type MemoryStore struct {
+	mu           sync.Mutex
+	tasks        map[int64]*Task
+	lastTaskID   int64
+	holidays     map[string]*Holiday
+	completions  map[int64]*Completion
+	lastComplete int64
+	projects     map[int64]*Project
+	lastProject  int64
+	lastItem     int64
+	// depsSince — время появления связей: задача → блокер → отметка времени
+	depsSince map[string]map[string]string
+}
+
+// NewMemoryStore создаёт пустое хранилище в памяти
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tasks:       make(map[int64]*Task),
+		holidays:    make(map[string]*Holiday),
+		completions: make(map[int64]*Completion),
+		projects:    make(map[int64]*Project),
+		depsSince:   make(map[string]map[string]string),
+	}
+}
+
+// task возвращает задачу по строковому идентификатору
+func (s *MemoryStore) task(id string) (*Task, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	task, ok := s.tasks[n]
+	return task, ok
+}
+
+// copyTask возвращает копию, чтобы вызывающий код не менял хранилище напрямую
+func copyTask(task *Task) *Task {
+	c := *task
+	c.Tags = slices.Clone(task.Tags)
+	c.DependsOn = slices.Clone(task.DependsOn)
+	c.BlockedBy = nil
+	c.Blocks = nil
+	c.Items = nil
+	for _, item := range task.Items {
+		copied := *item
+		c.Items = append(c.Items, &copied)
+	}
+	c.Progress = progress(task.Items)
+	return &c
+}
+
+// listTask возвращает копию задачи для списков: с невыполненными блокерами и без пунктов чек-листа
+func (s *MemoryStore) listTask(task *Task) *Task {
+	c := copyTask(task)
+	c.Items = nil
+	for _, id := range task.DependsOn {
+		blocker, ok := s.task(id)
+		if !ok || blocker.Done || blocker.DeletedAt != "" {
+			continue
+		}
+		if blocker.Repeat != "" && s.completedSince(id, s.depsSince[task.ID][id]) {
+			continue
+		}
+		c.BlockedBy = append(c.BlockedBy, id)
+	}
+	return c
+}
+
+// completedSince проверяет, записано ли выполнение задачи id не раньше отметки since
+func (s *MemoryStore) completedSince(id, since string) bool {
+	for _, c := range s.completions {
+		if c.TaskID == id && c.CompletedAt >= since {
+			return true
+		}
+	}
+	return false
+}
+
+// setDeps заменяет зависимости задачи: оставшиеся связи сохраняют время появления, новые получают текущее
+func (s *MemoryStore) setDeps(task *Task, deps []string) {
+	task.DependsOn = deps
+	since := make(map[string]string, len(deps))
+	for _, dep := range deps {
+		if created, ok := s.depsSince[task.ID][dep]; ok {
+			since[dep] = created
+		} else {
+			since[dep] = now()
+		}
+	}
+	s.depsSince[task.ID] = since
+}
+
+// sortIDs возвращает идентификаторы по возрастанию без повторов, как их читает SQLStore
+func sortIDs(ids []string) []string {
+	if len(ids) == 0 {
+		return nil
+	}
+	ids = slices.Clone(ids)
+	slices.SortFunc(ids, func(a, b string) int { return cmp.Compare(parseID(a), parseID(b)) })
+	return slices.Compact(ids)
+}
+
+// dependsOn проверяет, зависит ли задача from от задачи id напрямую или через другие задачи
+func (s *MemoryStore) dependsOn(from, id string, seen map[string]bool) bool {
+	if from == id {
+		return true
+	}
+	if seen[from] {
+		return false
+	}
+	seen[from] = true
+	task, ok := s.task(from)
+	if !ok {
+		return false
+	}
+	for _, blocker := range task.DependsOn {
+		if s.dependsOn(blocker, id, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// purge окончательно удаляет задачу вместе со связями, в которых она блокер
+func (s *MemoryStore) purge(id int64) {
+	delete(s.tasks, id)
+	blocker := strconv.FormatInt(id, 10)
+	delete(s.depsSince, blocker)
+	for _, task := range s.tasks {
+		task.DependsOn = slices.DeleteFunc(task.DependsOn, func(dep string) bool { return dep == blocker })
+		delete(s.depsSince[task.ID], blocker)
+	}
+}
+
+// uniqueTags возвращает теги по алфавиту без повторов, как их читает SQLStore
+func uniqueTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	tags = slices.Clone(tags)
+	slices.Sort(tags)
+	return slices.Compact(tags)
+}
+
+// now возвращает текущее время в формате отметок задачи (UTC, RFC 3339)
+func now() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func (s *MemoryStore) AddTask(task *Task) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastTaskID++
+	stored := copyTask(task)
+	stored.ID = strconv.FormatInt(s.lastTaskID, 10)
+	stored.Done = false
+	stored.DeletedAt = ""
+	stored.CreatedAt = now()
+	stored.UpdatedAt = stored.CreatedAt
+	stored.Tags = uniqueTags(task.Tags)
+	s.setDeps(stored, sortIDs(task.DependsOn))
+	stored.Items = nil
+	s.setItems(stored, task.Items)
+	s.tasks[s.lastTaskID] = stored
+	return s.lastTaskID, nil
+}
+
+func (s *MemoryStore) RestoreTask(task *Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id, err := strconv.ParseInt(task.ID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("incorrect id for restoring task")
+	}
+	stored := copyTask(task)
+	stored.Tags = uniqueTags(task.Tags)
+	s.setDeps(stored, slices.DeleteFunc(sortIDs(task.DependsOn), func(dep string) bool {
+		_, ok := s.task(dep)
+		return !ok
+	}))
+	stored.Items = nil
+	s.setItems(stored, task.Items)
+	s.tasks[id] = stored
+	for _, dependent := range task.Blocks {
+		if other, ok := s.task(dependent); ok && !slices.Contains(other.DependsOn, task.ID) {
+			s.setDeps(other, sortIDs(append(other.DependsOn, task.ID)))
+		}
+	}
+	if id > s.lastTaskID {
+		s.lastTaskID = id
+	}
+	return nil
+}
+
+func (s *MemoryStore) GetTask(id string) (*Task, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.task(id)
+	if !ok || task.DeletedAt != "" {
+		return nil, sql.ErrNoRows
+	}
+	found := s.listTask(task)
+	found.Items = copyTask(task).Items
+	for _, other := range s.tasks {
+		if slices.Contains(other.DependsOn, task.ID) {
+			found.Blocks = append(found.Blocks, other.ID)
+		}
+	}
+	found.Blocks = sortIDs(found.Blocks)
+	return found, nil
+}
+
+func (s *MemoryStore) UpdateTask(task *Task) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.task(task.ID)
+	if !ok || stored.DeletedAt != "" {
+		return fmt.Errorf("incorrect id for updating task")
+	}
+	for _, blocker := range task.DependsOn {
+		if s.dependsOn(blocker, stored.ID, make(map[string]bool)) {
+			return ErrDependencyCycle
+		}
+	}
+	stored.Date = task.Date
+	stored.Title = task.Title
+	stored.Comment = task.Comment
+	stored.Repeat = task.Repeat
+	stored.Time = task.Time
+	stored.Duration = task.Duration
+	stored.Priority = task.Priority
+	stored.ProjectID = task.ProjectID
+	stored.Remaining = task.Remaining
+	stored.UpdatedAt = now()
+	if task.Tags != nil {
+		stored.Tags = uniqueTags(task.Tags)
+	}
+	if task.DependsOn != nil {
+		s.setDeps(stored, sortIDs(task.DependsOn))
+	}
+	return nil
+}
+
+func (s *MemoryStore) UpdateTaskDate(id string, date string, remaining int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.task(id)
+	if !ok || stored.DeletedAt != "" {
+		return fmt.Errorf("incorrect id for updating task date")
+	}
+	stored.Date = date
+	stored.Remaining = remaining
+	stored.UpdatedAt = now()
+	return nil
+}
+
+func (s *MemoryStore) MarkTaskDone(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.task(id)
+	if !ok || stored.DeletedAt != "" {
+		return fmt.Errorf("incorrect id for marking task done")
+	}
+	stored.Done = true
+	stored.UpdatedAt = now()
+	return nil
+}
+
+func (s *MemoryStore) DeleteTask(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.task(id)
+	if !ok || stored.DeletedAt != "" {
+		return fmt.Errorf("incorrect id for deleting task")
+	}
+	stored.DeletedAt = now()
+	return nil
+}
+
+func (s *MemoryStore) Tasks(opts TasksOptions) (*TaskPage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	q, err := parseTaskQuery(opts.Search)
+	if err != nil {
+		return nil, err
+	}
+	node := q.text
+	keys, err := parseSort(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	ranked := node != nil && opts.Sort == ""
+	cursor, err := decodeCursor(opts.Cursor, opts.Sort, keys, ranked)
+	if err != nil {
+		return nil, err
+	}
+
+	tasks := make([]*Task, 0)
+	rank := make(map[*Task]int)
+	options := &taskQuery{filters: optionFilters(opts)}
+	for _, task := range s.tasks {
+		if opts.ProjectID == "" && s.archived(task) {
+			continue
+		}
+		found := s.listTask(task)
+		if task.Done || task.DeletedAt != "" || !q.match(task) || !options.match(found) ||
+			(opts.From != "" && task.Date < opts.From) || (opts.To != "" && task.Date > opts.To) {
+			continue
+		}
+		if node != nil {
+			if !node.matchWords(append(splitWords(task.Title), splitWords(task.Comment)...)) {
+				continue
+			}
+			// Совпадения в заголовке весят больше, чем в комментарии
+			titleHits := node.hits(task.Title)
+			rank[found] = 10*titleHits + node.hits(task.Comment)
+			if titleHits > 0 {
+				found.Snippet = node.highlight(task.Title)
+			} else {
+				found.Snippet = node.highlight(task.Comment)
+			}
+		}
+		tasks = append(tasks, found)
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if ranked && rank[tasks[i]] != rank[tasks[j]] {
+			return rank[tasks[i]] > rank[tasks[j]]
+		}
+		return compareTasks(keys, tasks[i], tasks[j]) < 0
+	})
+
+	page := &TaskPage{Total: len(tasks)}
+	offset := 0
+	if cursor != nil && ranked {
+		offset = min(cursor.Offset, len(tasks))
+		tasks = tasks[offset:]
+	} else if cursor != nil {
+		after := tasks[:0]
+		for _, task := range tasks {
+			if cursor.after(keys, task) {
+				after = append(after, task)
+			}
+		}
+		tasks = after
+	}
+
+	if len(tasks) > opts.Limit {
+		tasks = tasks[:opts.Limit]
+		page.NextCursor = nextCursor(tasks, opts.Sort, keys, ranked, offset)
+	}
+	page.Tasks = tasks
+	return page, nil
+}
+
+func (s *MemoryStore) TrashedTasks(limit int, cursor string) (*TaskPage, error) {
+	c, err := decodeNewestCursor(cursor, trashList)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tasks := make([]*Task, 0)
+	for id, task := range s.tasks {
+		if task.DeletedAt != "" && (c == nil || c.older(task.DeletedAt, id)) {
+			tasks = append(tasks, s.listTask(task))
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].DeletedAt != tasks[j].DeletedAt {
+			return tasks[i].DeletedAt > tasks[j].DeletedAt
+		}
+		return taskID(tasks[i]) > taskID(tasks[j])
+	})
+
+	if len(tasks) > limit+1 {
+		tasks = tasks[:limit+1]
+	}
+	return trashPage(tasks, limit), nil
+}
+
+func (s *MemoryStore) RestoreTrashedTask(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.task(id)
+	if !ok || stored.DeletedAt == "" {
+		return fmt.Errorf("incorrect id for restoring task")
+	}
+	stored.DeletedAt = ""
+	return nil
+}
+
+func (s *MemoryStore) PurgeTask(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.task(id)
+	if !ok {
+		return fmt.Errorf("incorrect id for purging task")
+	}
+	s.purge(taskID(stored))
+	return nil
+}
+
+func (s *MemoryStore) PurgeTrashedTask(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.task(id)
+	if !ok || stored.DeletedAt == "" {
+		return fmt.Errorf("incorrect id for purging task")
+	}
+	s.purge(taskID(stored))
+	return nil
+}
+
+func (s *MemoryStore) PurgeTrash(before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	limit := before.UTC().Format(time.RFC3339)
+	var count int64
+	for id, task := range s.tasks {
+		if task.DeletedAt != "" && task.DeletedAt < limit {
+			s.purge(id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) EmptyTrash() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var count int64
+	for id, task := range s.tasks {
+		if task.DeletedAt != "" {
+			s.purge(id)
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryStore) Tags() ([]*Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int)
+	for _, task := range s.tasks {
+		if task.Done || task.DeletedAt != "" {
+			continue
+		}
+		for _, name := range task.Tags {
+			counts[name]++
+		}
+	}
+
+	tags := make([]*Tag, 0, len(counts))
+	for name, count := range counts {
+		tags = append(tags, &Tag{Name: name, Count: count})
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (s *MemoryStore) MergeTags(names []string, into string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, name := range names {
+		if name == into {
+			continue
+		}
+		found := false
+		for _, task := range s.tasks {
+			found = found || slices.Contains(task.Tags, name)
+		}
+		if !found {
+			return ErrTagNotFound
+		}
+	}
+
+	for _, task := range s.tasks {
+		merged := slices.DeleteFunc(slices.Clone(task.Tags), func(tag string) bool {
+			return slices.Contains(names, tag)
+		})
+		if len(merged) != len(task.Tags) {
+			task.Tags = uniqueTags(append(merged, into))
+		}
+	}
+	return nil
+}
+
+// setItems заменяет чек-лист хранимой задачи копиями items, назначая идентификаторы новым пунктам
+func (s *MemoryStore) setItems(stored *Task, items []*TaskItem) {
+	stored.Items = nil
+	for i, item := range items {
+		c := *item
+		if c.ID == "" {
+			s.lastItem++
+			c.ID = strconv.FormatInt(s.lastItem, 10)
+		} else if id := parseID(c.ID); id > s.lastItem {
+			s.lastItem = id
+		}
+		c.TaskID = stored.ID
+		if c.Position == 0 {
+			c.Position = i + 1
+		}
+		stored.Items = append(stored.Items, &c)
+	}
+	sortItems(stored.Items)
+}
+
+// sortItems упорядочивает пункты так же, как SQLStore: по позиции, затем по идентификатору
+func sortItems(items []*TaskItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Position != items[j].Position {
+			return items[i].Position < items[j].Position
+		}
+		return parseID(items[i].ID) < parseID(items[j].ID)
+	})
+}
+
+// item возвращает пункт чек-листа и задачу, к которой он относится
+func (s *MemoryStore) item(id string) (*TaskItem, *Task, bool) {
+	for _, task := range s.tasks {
+		for _, item := range task.Items {
+			if item.ID == id {
+				return item, task, true
+			}
+		}
+	}
+	return nil, nil, false
+}
+
+func (s *MemoryStore) AddTaskItem(item *TaskItem) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	task, ok := s.task(item.TaskID)
+	if !ok {
+		return 0, fmt.Errorf("incorrect task id for adding task item")
+	}
+	position := 1
+	for _, stored := range task.Items {
+		position = max(position, stored.Position+1)
+	}
+
+	s.lastItem++
+	stored := *item
+	stored.ID = strconv.FormatInt(s.lastItem, 10)
+	stored.Position = position
+	task.Items = append(task.Items, &stored)
+	return s.lastItem, nil
+}
+
+func (s *MemoryStore) GetTaskItem(id string) (*TaskItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, _, ok := s.item(id)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	c := *item
+	return &c, nil
+}
+
+func (s *MemoryStore) UpdateTaskItem(item *TaskItem) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, task, ok := s.item(item.ID)
+	if !ok {
+		return fmt.Errorf("incorrect id for updating task item")
+	}
+	if position := min(item.Position, len(task.Items)); position > 0 && position != stored.Position {
+		for _, other := range task.Items {
+			switch {
+			case other == stored:
+			case position > stored.Position && other.Position > stored.Position && other.Position <= position:
+				other.Position--
+			case position < stored.Position && other.Position >= position && other.Position < stored.Position:
+				other.Position++
+			}
+		}
+		stored.Position = position
+	}
+	stored.Title = item.Title
+	stored.Done = item.Done
+	sortItems(task.Items)
+	return nil
+}
+
+func (s *MemoryStore) DeleteTaskItem(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, task, ok := s.item(id)
+	if !ok {
+		return fmt.Errorf("incorrect id for deleting task item")
+	}
+	task.Items = slices.DeleteFunc(task.Items, func(item *TaskItem) bool { return item == stored })
+	for _, other := range task.Items {
+		if other.Position > stored.Position {
+			other.Position--
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) TaskItems(taskID string) ([]*TaskItem, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	items := make([]*TaskItem, 0)
+	if task, ok := s.task(taskID); ok {
+		items = append(items, copyTask(task).Items...)
+	}
+	return items, nil
+}
+
+func (s *MemoryStore) ResetTaskItems(taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if task, ok := s.task(taskID); ok {
+		for _, item := range task.Items {
+			item.Done = false
+		}
+	}
+	return nil
+}
+
+// archived возвращает true, если задача в архивном проекте
+func (s *MemoryStore) archived(task *Task) bool {
+	p, ok := s.projects[projectID(task)]
+	return ok && p.Archived
+}
+
+// project возвращает проект по строковому идентификатору
+func (s *MemoryStore) project(id string) (*Project, bool) {
+	n, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	p, ok := s.projects[n]
+	return p, ok
+}
+
+// projectTasks возвращает число активных задач проекта
+func (s *MemoryStore) projectTasks(id int64) int {
+	count := 0
+	for _, task := range s.tasks {
+		if projectID(task) == id && !task.Done && task.DeletedAt == "" {
+			count++
+		}
+	}
+	return count
+}
+
+func (s *MemoryStore) AddProject(p *Project) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastProject++
+	project := *p
+	project.ID = strconv.FormatInt(s.lastProject, 10)
+	project.Tasks = 0
+	s.projects[s.lastProject] = &project
+	return s.lastProject, nil
+}
+
+func (s *MemoryStore) GetProject(id string) (*Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.project(id)
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	project := *stored
+	project.Tasks = s.projectTasks(parseID(id))
+	return &project, nil
+}
+
+func (s *MemoryStore) UpdateProject(p *Project) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.project(p.ID)
+	if !ok {
+		return fmt.Errorf("incorrect id for updating project")
+	}
+	stored.Name = p.Name
+	stored.Color = p.Color
+	stored.Archived = p.Archived
+	return nil
+}
+
+func (s *MemoryStore) DeleteProject(id, moveTo string, trashTasks bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.project(id)
+	if !ok {
+		return fmt.Errorf("incorrect id for deleting project")
+	}
+	delete(s.projects, parseID(stored.ID))
+
+	for _, task := range s.tasks {
+		if task.ProjectID != stored.ID {
+			continue
+		}
+		if trashTasks && task.DeletedAt == "" {
+			task.ProjectID = ""
+			task.DeletedAt = now()
+			continue
+		}
+		task.ProjectID = ""
+		if parseID(moveTo) != 0 {
+			task.ProjectID = moveTo
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Projects(archived bool) ([]*Project, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	projects := make([]*Project, 0)
+	for id, p := range s.projects {
+		if p.Archived && !archived {
+			continue
+		}
+		project := *p
+		project.Tasks = s.projectTasks(id)
+		projects = append(projects, &project)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Name != projects[j].Name {
+			return projects[i].Name < projects[j].Name
+		}
+		return parseID(projects[i].ID) < parseID(projects[j].ID)
+	})
+	return projects, nil
+}
+
+func (s *MemoryStore) AddHoliday(h *Holiday) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	holiday := *h
+	s.holidays[h.Date] = &holiday
+	return nil
+}
+
+func (s *MemoryStore) DeleteHoliday(date string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.holidays[date]; !ok {
+		return fmt.Errorf("incorrect date for deleting holiday")
+	}
+	delete(s.holidays, date)
+	return nil
+}
+
+func (s *MemoryStore) Holidays(from, to string) ([]*Holiday, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	holidays := make([]*Holiday, 0)
+	for _, h := range s.holidays {
+		if (from != "" && h.Date < from) || (to != "" && h.Date > to) {
+			continue
+		}
+		holiday := *h
+		holidays = append(holidays, &holiday)
+	}
+	sort.Slice(holidays, func(i, j int) bool {
+		return holidays[i].Date < holidays[j].Date
+	})
+	return holidays, nil
+}
+
+func (s *MemoryStore) HolidaySet() (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	set := make(map[string]bool, len(s.holidays))
+	for date := range s.holidays {
+		set[date] = true
+	}
+	return set, nil
+}
+
+func (s *MemoryStore) AddCompletion(c *Completion) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastComplete++
+	completion := *c
+	completion.ID = strconv.FormatInt(s.lastComplete, 10)
+	s.completions[s.lastComplete] = &completion
+	return s.lastComplete, nil
+}
+
+func (s *MemoryStore) DeleteCompletion(id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.completions, id)
+	return nil
+}
+
+func (s *MemoryStore) Completions(opts CompletionsOptions) (*CompletionPage, error) {
+	cursor, err := decodeNewestCursor(opts.Cursor, completionsList)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	completions := make([]*Completion, 0)
+	for id, c := range s.completions {
+		if (opts.From != "" && c.CompletedAt < opts.From) || (opts.To != "" && c.CompletedAt >= opts.To) ||
+			(opts.TaskID != "" && c.TaskID != opts.TaskID) || (cursor != nil && !cursor.older(c.CompletedAt, id)) {
+			continue
+		}
+		completion := *c
+		completions = append(completions, &completion)
+	}
+	sort.Slice(completions, func(i, j int) bool {
+		if completions[i].CompletedAt != completions[j].CompletedAt {
+			return completions[i].CompletedAt > completions[j].CompletedAt
+		}
+		return parseID(completions[i].ID) > parseID(completions[j].ID)
+	})
+
+	if len(completions) > opts.Limit+1 {
+		completions = completions[:opts.Limit+1]
+	}
+	return completionPage(completions, opts.Limit), nil
+}
+
+// InTx выполняет fn и при ошибке возвращает хранилище в состояние до вызова.
+// В отличие от базы данных, изменения из других горутин во время fn тоже отменяются.
+func (s *MemoryStore) InTx(fn func(tx TaskStore) error) error {
+	s.mu.Lock()
+	saved := s.snapshot()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.tasks, s.lastTaskID = saved.tasks, saved.lastTaskID
+		s.holidays = saved.holidays
+		s.completions, s.lastComplete = saved.completions, saved.lastComplete
+		s.projects, s.lastProject = saved.projects, saved.lastProject
+		s.lastItem = saved.lastItem
+		s.depsSince = saved.depsSince
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// snapshot возвращает копию всех данных хранилища
+func (s *MemoryStore) snapshot() *MemoryStore {
+	c := &MemoryStore{
+		tasks:        make(map[int64]*Task, len(s.tasks)),
+		lastTaskID:   s.lastTaskID,
+		holidays:     make(map[string]*Holiday, len(s.holidays)),
+		completions:  make(map[int64]*Completion, len(s.completions)),
+		lastComplete: s.lastComplete,
+		projects:     make(map[int64]*Project, len(s.projects)),
+		lastProject:  s.lastProject,
+		lastItem:     s.lastItem,
+		depsSince:    make(map[string]map[string]string, len(s.depsSince)),
+	}
+	for id, task := range s.tasks {
+		c.tasks[id] = copyTask(task)
+	}
+	for date, h := range s.holidays {
+		holiday := *h
+		c.holidays[date] = &holiday
+	}
+	for id, completion := range s.completions {
+		copied := *completion
+		c.completions[id] = &copied
+	}
+	for id, p := range s.projects {
+		project := *p
+		c.projects[id] = &project
+	}
+	for id, since := range s.depsSince {
+		c.depsSince[id] = maps.Clone(since)
+	}
+	return c
+}
+
+// SchemaVersion у хранилища в памяти всегда совпадает с последней версией схемы
+func (s *MemoryStore) SchemaVersion() (int, error) {
+	return LatestSchemaVersion(), nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
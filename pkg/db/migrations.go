@@ -0,0 +1,319 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// migration — шаг изменения схемы. Шаги применяются строго по возрастанию версии.
+type migration struct {
+	version int
+	name    string
+	up      migrationStep
+}
+
+// migrationStep изменяет схему внутри транзакции с учётом диалекта базы
+type migrationStep func(tx *sql.Tx, d *dialect) error
+
+// migrations — все шаги схемы. Новые шаги добавляются только в конец списка,
+// уже выпущенные шаги не меняются. Таблицы описываются в синтаксисе SQLite,
+// для других баз его переводит dialect.ddl.
+var migrations = []migration{
+	{1, "create scheduler", execSQL(`
+		CREATE TABLE IF NOT EXISTS scheduler (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			date CHAR(8) NOT NULL DEFAULT '',
+			title VARCHAR(255) NOT NULL DEFAULT '',
+			comment TEXT NOT NULL DEFAULT '',
+			repeat VARCHAR(128) NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_scheduler_date ON scheduler(date);
+	`)},
+	{2, "create holidays", execSQL(`
+		CREATE TABLE IF NOT EXISTS holidays (
+			date CHAR(8) PRIMARY KEY,
+			title VARCHAR(255) NOT NULL DEFAULT ''
+		);
+	`)},
+	{3, "add task time and duration", addColumns("scheduler",
+		"time CHAR(5) NOT NULL DEFAULT ''",
+		"duration INTEGER NOT NULL DEFAULT 0",
+	)},
+	{4, "add remaining repeats", addColumns("scheduler",
+		"remaining INTEGER NOT NULL DEFAULT 0",
+	)},
+	{5, "create task completions", steps(
+		addColumns("scheduler", "done INTEGER NOT NULL DEFAULT 0"),
+		execSQL(`
+			CREATE TABLE IF NOT EXISTS task_completions (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				task_id INTEGER NOT NULL,
+				title VARCHAR(255) NOT NULL DEFAULT '',
+				date CHAR(8) NOT NULL DEFAULT '',
+				completed_at VARCHAR(32) NOT NULL DEFAULT ''
+			);
+			CREATE INDEX IF NOT EXISTS idx_task_completions_completed_at ON task_completions(completed_at);
+		`),
+	)},
+	{6, "add trash", addColumns("scheduler",
+		"deleted_at VARCHAR(32) NOT NULL DEFAULT ''",
+	)},
+	{7, "create full-text search", forDialect(map[*dialect]migrationStep{
+		// Внешнее содержимое: FTS5 хранит только индекс, текст берётся из scheduler
+		sqliteDialect: execSQL(`
+			CREATE VIRTUAL TABLE IF NOT EXISTS scheduler_fts
+				USING fts5(title, comment, content='scheduler', content_rowid='id');
+			CREATE TRIGGER IF NOT EXISTS scheduler_fts_insert AFTER INSERT ON scheduler BEGIN
+				INSERT INTO scheduler_fts(rowid, title, comment) VALUES (new.id, new.title, new.comment);
+			END;
+			CREATE TRIGGER IF NOT EXISTS scheduler_fts_delete AFTER DELETE ON scheduler BEGIN
+				INSERT INTO scheduler_fts(scheduler_fts, rowid, title, comment)
+					VALUES ('delete', old.id, old.title, old.comment);
+			END;
+			CREATE TRIGGER IF NOT EXISTS scheduler_fts_update AFTER UPDATE OF title, comment ON scheduler BEGIN
+				INSERT INTO scheduler_fts(scheduler_fts, rowid, title, comment)
+					VALUES ('delete', old.id, old.title, old.comment);
+				INSERT INTO scheduler_fts(rowid, title, comment) VALUES (new.id, new.title, new.comment);
+			END;
+			INSERT INTO scheduler_fts(scheduler_fts) VALUES ('rebuild');
+		`),
+		postgresDialect: execSQL(`
+			CREATE INDEX IF NOT EXISTS idx_scheduler_search ON scheduler USING GIN (` + postgresSearchVector + `);
+		`),
+	})},
+	{8, "add task timestamps", addColumns("scheduler",
+		"created_at VARCHAR(32) NOT NULL DEFAULT ''",
+		"updated_at VARCHAR(32) NOT NULL DEFAULT ''",
+	)},
+	{9, "create tags", steps(
+		execSQL(`
+			CREATE TABLE IF NOT EXISTS tags (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name VARCHAR(64) NOT NULL UNIQUE
+			);
+			CREATE TABLE IF NOT EXISTS task_tags (
+				task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+				tag_id INTEGER NOT NULL REFERENCES tags(id) ON DELETE CASCADE,
+				PRIMARY KEY (task_id, tag_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_task_tags_tag_id ON task_tags(tag_id);
+		`),
+		// SQLite проверяет внешние ключи только с PRAGMA foreign_keys, поэтому связи удаляет триггер
+		forDialect(map[*dialect]migrationStep{
+			sqliteDialect: execSQL(`
+				CREATE TRIGGER IF NOT EXISTS scheduler_tags_delete AFTER DELETE ON scheduler BEGIN
+					DELETE FROM task_tags WHERE task_id = old.id;
+				END;
+			`),
+			postgresDialect: steps(),
+		}),
+	)},
+	{10, "add task priority", addColumns("scheduler",
+		"priority INTEGER NOT NULL DEFAULT 0",
+	)},
+	{11, "create projects", steps(
+		execSQL(`
+			CREATE TABLE IF NOT EXISTS projects (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				name VARCHAR(255) NOT NULL DEFAULT '',
+				color VARCHAR(7) NOT NULL DEFAULT '',
+				archived INTEGER NOT NULL DEFAULT 0,
+				created_at VARCHAR(32) NOT NULL DEFAULT ''
+			);
+		`),
+		// 0 — задача без проекта
+		addColumns("scheduler", "project_id INTEGER NOT NULL DEFAULT 0"),
+		execSQL(`CREATE INDEX IF NOT EXISTS idx_scheduler_project_id ON scheduler(project_id);`),
+	)},
+	{12, "create task items", steps(
+		execSQL(`
+			CREATE TABLE IF NOT EXISTS task_items (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+				title VARCHAR(255) NOT NULL DEFAULT '',
+				done INTEGER NOT NULL DEFAULT 0,
+				position INTEGER NOT NULL DEFAULT 0
+			);
+			CREATE INDEX IF NOT EXISTS idx_task_items_task_id ON task_items(task_id, position);
+		`),
+		forDialect(map[*dialect]migrationStep{
+			sqliteDialect: execSQL(`
+				CREATE TRIGGER IF NOT EXISTS scheduler_items_delete AFTER DELETE ON scheduler BEGIN
+					DELETE FROM task_items WHERE task_id = old.id;
+				END;
+			`),
+			postgresDialect: steps(),
+		}),
+	)},
+	{13, "create task dependencies", steps(
+		execSQL(`
+			CREATE TABLE IF NOT EXISTS task_deps (
+				task_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+				blocker_id INTEGER NOT NULL REFERENCES scheduler(id) ON DELETE CASCADE,
+				PRIMARY KEY (task_id, blocker_id)
+			);
+			CREATE INDEX IF NOT EXISTS idx_task_deps_blocker_id ON task_deps(blocker_id);
+		`),
+		forDialect(map[*dialect]migrationStep{
+			sqliteDialect: execSQL(`
+				CREATE TRIGGER IF NOT EXISTS scheduler_deps_delete AFTER DELETE ON scheduler BEGIN
+					DELETE FROM task_deps WHERE task_id = old.id OR blocker_id = old.id;
+				END;
+			`),
+			postgresDialect: steps(),
+		}),
+	)},
+	// created_at нужен повторяющимся блокерам: такой блокер снимается выполнением,
+	// записанным после появления связи. Прежним связям ставится время миграции.
+	{14, "add task dependency time", steps(
+		addColumns("task_deps", "created_at VARCHAR(32) NOT NULL DEFAULT ''"),
+		execSQL(`CREATE INDEX IF NOT EXISTS idx_task_completions_task_id ON task_completions(task_id, completed_at);`),
+		func(tx *sql.Tx, d *dialect) error {
+			_, err := tx.Exec(d.rebind(`UPDATE task_deps SET created_at = ? WHERE created_at = ''`), now())
+			return err
+		},
+	)},
+}
+
+// LatestSchemaVersion возвращает версию схемы, которую поддерживает приложение
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+// execSQL возвращает шаг, выполняющий SQL-скрипт
+func execSQL(script string) migrationStep {
+	return func(tx *sql.Tx, d *dialect) error {
+		_, err := tx.Exec(d.ddl(script))
+		return err
+	}
+}
+
+// steps объединяет несколько шагов в один
+func steps(fns ...migrationStep) migrationStep {
+	return func(tx *sql.Tx, d *dialect) error {
+		for _, fn := range fns {
+			if err := fn(tx, d); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// forDialect возвращает шаг, который выполняется по-своему для каждой базы
+func forDialect(byDialect map[*dialect]migrationStep) migrationStep {
+	return func(tx *sql.Tx, d *dialect) error {
+		step, ok := byDialect[d]
+		if !ok {
+			return fmt.Errorf("migration is not defined for this database")
+		}
+		return step(tx, d)
+	}
+}
+
+// addColumns возвращает шаг, добавляющий столбцы в таблицу. Уже существующие столбцы
+// пропускаются: их могли добавить при запуске версии без учёта миграций.
+func addColumns(table string, definitions ...string) migrationStep {
+	return func(tx *sql.Tx, d *dialect) error {
+		existing, err := tableColumns(tx, d, table)
+		if err != nil {
+			return err
+		}
+		for _, def := range definitions {
+			name := strings.Fields(def)[0]
+			if existing[name] {
+				continue
+			}
+			if _, err := tx.Exec(d.ddl(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s`, table, def))); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func tableColumns(tx *sql.Tx, d *dialect, table string) (map[string]bool, error) {
+	rows, err := tx.Query(d.rebind(d.columnsQuery), table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		columns[name] = true
+	}
+	return columns, rows.Err()
+}
+
+// rowQuerier — общий интерфейс *sql.DB и *sql.Tx для запросов одной строки
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// schemaVersion возвращает текущую версию схемы базы (0 — миграции ещё не применялись)
+func schemaVersion(q rowQuerier) (int, error) {
+	var version int
+	err := q.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_version`).Scan(&version)
+	return version, err
+}
+
+// SchemaVersion возвращает текущую версию схемы открытой базы
+func (s *SQLStore) SchemaVersion() (int, error) {
+	return schemaVersion(s.db)
+}
+
+// migrate применяет недостающие шаги схемы в одной транзакции:
+// при ошибке база остаётся в прежнем состоянии
+func migrate(database *sql.DB, d *dialect) error {
+	tx, err := database.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if d.migrationLock != "" {
+		if _, err := tx.Exec(d.migrationLock); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name VARCHAR(255) NOT NULL DEFAULT '',
+		applied_at VARCHAR(32) NOT NULL DEFAULT ''
+	)`)
+	if err != nil {
+		return err
+	}
+
+	current, err := schemaVersion(tx)
+	if err != nil {
+		return err
+	}
+	if current > LatestSchemaVersion() {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", current, LatestSchemaVersion())
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := m.up(tx, d); err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		_, err = tx.Exec(d.rebind(`INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)`),
+			m.version, m.name, time.Now().UTC().Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,11 @@
+package db
+
+import (
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// OpenPostgres подключается к PostgreSQL по строке подключения dsn
+// и приводит схему базы к последней версии
+func OpenPostgres(dsn string) (*SQLStore, error) {
+	return open("pgx", dsn, postgresDialect)
+}
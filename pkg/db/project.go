@@ -0,0 +1,142 @@
+package db
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Project — список, в который собраны задачи
+type Project struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+	// Archived — проект в архиве: его задачи не показываются в общем списке
+	Archived bool `json:"archived"`
+	// Tasks — число активных задач проекта
+	Tasks int `json:"tasks"`
+}
+
+// projectColumns — столбцы проекта в порядке, который ожидает scanProject
+const projectColumns = `projects.id, projects.name, projects.color, projects.archived`
+
+func scanProject(row rowScanner, extra ...any) (*Project, error) {
+	var p Project
+	var id int64
+	if err := row.Scan(append([]any{&id, &p.Name, &p.Color, &p.Archived}, extra...)...); err != nil {
+		return nil, err
+	}
+	p.ID = strconv.FormatInt(id, 10)
+	return &p, nil
+}
+
+// projectID возвращает числовой идентификатор проекта задачи; 0 — задача без проекта
+func projectID(task *Task) int64 {
+	return parseID(task.ProjectID)
+}
+
+func (s *SQLStore) AddProject(p *Project) (int64, error) {
+	var id int64
+	query := `INSERT INTO projects (name, color, archived, created_at) VALUES (?, ?, ?, ?) RETURNING id`
+	err := s.queryRow(query, p.Name, p.Color, boolInt(p.Archived), time.Now().UTC().Format(time.RFC3339)).Scan(&id)
+	return id, err
+}
+
+// GetProject возвращает проект вместе с числом активных задач
+func (s *SQLStore) GetProject(id string) (*Project, error) {
+	query := `SELECT ` + projectColumns + ` FROM projects WHERE id = ?`
+	p, err := scanProject(s.queryRow(query, id))
+	if err != nil {
+		return nil, err
+	}
+	query = `SELECT COUNT(*) FROM scheduler WHERE project_id = ? AND done = 0 AND deleted_at = ''`
+	if err := s.queryRow(query, id).Scan(&p.Tasks); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (s *SQLStore) UpdateProject(p *Project) error {
+	query := `UPDATE projects SET name = ?, color = ?, archived = ? WHERE id = ?`
+	res, err := s.exec(query, p.Name, p.Color, boolInt(p.Archived), p.ID)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("incorrect id for updating project")
+	}
+
+	return nil
+}
+
+// DeleteProject удаляет проект. Его задачи переходят в проект moveTo ("" — без проекта)
+// или, если trashTasks, перемещаются в корзину без проекта.
+func (s *SQLStore) DeleteProject(id, moveTo string, trashTasks bool) error {
+	return s.inTx(func(tx *SQLStore) error {
+		res, err := tx.exec(`DELETE FROM projects WHERE id = ?`, id)
+		if err != nil {
+			return err
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return fmt.Errorf("incorrect id for deleting project")
+		}
+
+		if trashTasks {
+			query := `UPDATE scheduler SET project_id = 0, deleted_at = ? WHERE project_id = ? AND deleted_at = ''`
+			if _, err := tx.exec(query, time.Now().UTC().Format(time.RFC3339), id); err != nil {
+				return err
+			}
+		}
+
+		// Задачи в корзине и выполненные тоже не должны ссылаться на удалённый проект
+		_, err = tx.exec(`UPDATE scheduler SET project_id = ? WHERE project_id = ?`, parseID(moveTo), id)
+		return err
+	})
+}
+
+// Projects возвращает проекты по названию с числом активных задач; архивные — только если archived
+func (s *SQLStore) Projects(archived bool) ([]*Project, error) {
+	projects := make([]*Project, 0)
+
+	where := conditions{}
+	if !archived {
+		where = append(where, "projects.archived = 0")
+	}
+	query := `SELECT ` + projectColumns + `, COUNT(scheduler.id) FROM projects
+		LEFT JOIN scheduler ON scheduler.project_id = projects.id AND scheduler.done = 0 AND scheduler.deleted_at = ''` +
+		where.sql() + `
+		GROUP BY ` + projectColumns + ` ORDER BY projects.name, projects.id`
+	rows, err := s.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tasks int
+		p, err := scanProject(rows, &tasks)
+		if err != nil {
+			return nil, err
+		}
+		p.Tasks = tasks
+		projects = append(projects, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
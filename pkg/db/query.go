@@ -0,0 +1,321 @@
+package db
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// taskQuery — разобранный запрос к списку задач: условия вида поле:значение
+// и полнотекстовая часть из остальных слов
+type taskQuery struct {
+	filters []taskFilter
+	text    *searchNode
+}
+
+// taskFilter — одно условие запроса; negate соответствует записи -поле:значение
+type taskFilter struct {
+	field  string
+	value  string
+	text   *searchNode
+	negate bool
+	// numbers — числа условия priority: одно для сравнения value или список для value "in"
+	numbers []int
+}
+
+// filterToken — условие вида поле:значение, необязательно с минусом перед полем
+var filterToken = regexp.MustCompile(`^(-?)([a-z]+):(.*)$`)
+
+// filterFields — поля условий запроса; лексема с другим полем (re:встреча, http://…) ищется как текст
+var filterFields = map[string]bool{
+	"title": true, "comment": true, "before": true, "after": true, "date": true,
+	"repeat": true, "has": true, "tag": true, "priority": true,
+}
+
+// priorityValue — значение условия priority: с необязательным сравнением
+var priorityValue = regexp.MustCompile(`^(>=|<=|>|<)?(\d)$`)
+
+// repeatKinds — виды правил повторения для условия repeat:
+var repeatKinds = map[string]bool{"d": true, "bd": true, "w": true, "m": true, "mw": true, "y": true, "rrule": true}
+
+// hasFields — поля задачи для условия has:
+var hasFields = map[string]string{"comment": "comment", "repeat": "repeat", "time": "time"}
+
+// splitQuery разбивает запрос на лексемы по пробелам; пробелы внутри кавычек сохраняются
+func splitQuery(s string) []string {
+	var tokens []string
+	var b strings.Builder
+	quoted := false
+	for _, r := range s {
+		if r == '"' {
+			quoted = !quoted
+		}
+		if unicode.IsSpace(r) && !quoted {
+			if b.Len() > 0 {
+				tokens = append(tokens, b.String())
+				b.Reset()
+			}
+			continue
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() > 0 {
+		tokens = append(tokens, b.String())
+	}
+	return tokens
+}
+
+// parseQueryDate принимает дату в формате 20060102 или 02.01.2006
+func parseQueryDate(s string) (string, bool) {
+	for _, layout := range []string{"20060102", "02.01.2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Format("20060102"), true
+		}
+	}
+	return "", false
+}
+
+// parseTaskQuery разбирает запрос к списку задач. Поддерживаются условия
+//
+//	title:слово, comment:"фраза" — слова в заголовке или комментарии (как в полнотекстовом поиске)
+//	before:20261101, after:20261001, date:20261015 — дата задачи раньше, позже или равна
+//	repeat:w — вид правила повторения (d, bd, w, m, mw, y, rrule)
+//	has:comment, has:repeat, has:time — поле задачи заполнено
+//	tag:работа — задача с тегом
+//	priority:2, priority:>=2 — приоритет равен числу или сравнивается с ним (>, >=, <, <=)
+//
+// Минус перед условием (-has:repeat) или словом (-молоко) исключает совпадения.
+// Дата в формате 02.01.2006 ищется точно, остальные слова, в том числе с неизвестным
+// полем (re:встреча), — полнотекстовым поиском.
+func parseTaskQuery(s string) (*taskQuery, error) {
+	q := &taskQuery{}
+	var text []string
+
+	for _, token := range splitQuery(s) {
+		if date, ok := parseQueryDate(token); ok && strings.Contains(token, ".") {
+			q.filters = append(q.filters, taskFilter{field: "date", value: date})
+			continue
+		}
+
+		m := filterToken.FindStringSubmatch(token)
+		if m == nil || !filterFields[m[2]] {
+			// Исключённое слово проверяется отдельно, поэтому запрос может состоять только из исключений
+			if len(token) > 1 && strings.HasPrefix(token, "-") {
+				node, err := parseSearch(token[1:])
+				if err != nil {
+					return nil, err
+				}
+				q.filters = append(q.filters, taskFilter{field: "text", text: node, negate: true})
+			} else {
+				text = append(text, token)
+			}
+			continue
+		}
+
+		f, err := parseFilter(m[2], strings.Trim(m[3], `"`), m[3])
+		if err != nil {
+			return nil, err
+		}
+		f.negate = m[1] == "-"
+		q.filters = append(q.filters, f)
+	}
+
+	if len(text) > 0 {
+		node, err := parseTextSearch(strings.Join(text, " "))
+		if err != nil {
+			return nil, err
+		}
+		q.text = node
+	}
+	return q, nil
+}
+
+// parseFilter проверяет значение условия; raw — значение вместе с кавычками
+func parseFilter(field, value, raw string) (taskFilter, error) {
+	f := taskFilter{field: field, value: value}
+	if value == "" {
+		return f, fmt.Errorf("%w: empty value for %s:", ErrBadSearch, field)
+	}
+
+	switch field {
+	case "title", "comment":
+		node, err := parseSearch(raw)
+		if err != nil {
+			return f, err
+		}
+		f.text = node
+	case "before", "after", "date":
+		date, ok := parseQueryDate(value)
+		if !ok {
+			return f, fmt.Errorf("%w: invalid date %q for %s:", ErrBadSearch, value, field)
+		}
+		f.value = date
+	case "repeat":
+		f.value = strings.ToLower(value)
+		if !repeatKinds[f.value] {
+			return f, fmt.Errorf("%w: unknown repeat kind %q", ErrBadSearch, value)
+		}
+	case "has":
+		if _, ok := hasFields[value]; !ok {
+			return f, fmt.Errorf("%w: unknown field %q for has:", ErrBadSearch, value)
+		}
+	case "tag":
+		f.value = strings.ToLower(strings.TrimPrefix(value, "#"))
+	case "priority":
+		m := priorityValue.FindStringSubmatch(value)
+		if m == nil {
+			return f, fmt.Errorf("%w: invalid priority %q", ErrBadSearch, value)
+		}
+		n, _ := strconv.Atoi(m[2])
+		if n > MaxPriority {
+			return f, fmt.Errorf("%w: priority %d is greater than %d", ErrBadSearch, n, MaxPriority)
+		}
+		f.value, f.numbers = cmp.Or(m[1], "="), []int{n}
+	default:
+		return f, fmt.Errorf("%w: unknown field %q", ErrBadSearch, field)
+	}
+	return f, nil
+}
+
+// sql возвращает условие WHERE с параметрами ?
+func (f taskFilter) sql(d *dialect) (string, []any) {
+	var cond string
+	var args []any
+
+	switch f.field {
+	case "text":
+		match, arg := d.matchColumn("", f.text)
+		cond, args = match, []any{arg}
+	case "title", "comment":
+		match, arg := d.matchColumn(f.field, f.text)
+		cond, args = match, []any{arg}
+	case "before":
+		cond, args = "date < ?", []any{f.value}
+	case "after":
+		cond, args = "date > ?", []any{f.value}
+	case "date":
+		cond, args = "date = ?", []any{f.value}
+	case "repeat":
+		if f.value == "rrule" {
+			cond, args = "UPPER(repeat) LIKE ?", []any{"RRULE:%"}
+		} else {
+			cond, args = "(repeat = ? OR repeat LIKE ?)", []any{f.value, f.value + " %"}
+		}
+	case "has":
+		cond = hasFields[f.value] + " != ''"
+	case "priority":
+		if f.value == "in" {
+			cond = "priority IN (?" + strings.Repeat(", ?", len(f.numbers)-1) + ")"
+			for _, n := range f.numbers {
+				args = append(args, n)
+			}
+		} else {
+			cond, args = "priority "+f.value+" ?", []any{f.numbers[0]}
+		}
+	case "project":
+		cond, args = "project_id = ?", []any{parseID(f.value)}
+	case "blocked":
+		cond = "id IN (SELECT task_deps.task_id FROM task_deps JOIN scheduler blocker ON blocker.id = task_deps.blocker_id WHERE " +
+			openBlocker + ")"
+	case "tag":
+		cond, args = "id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.name = ?)",
+			[]any{f.value}
+	}
+	return f.wrap(cond), args
+}
+
+func (f taskFilter) wrap(cond string) string {
+	if f.negate {
+		return "NOT (" + cond + ")"
+	}
+	return cond
+}
+
+// match проверяет условие для задачи в памяти
+func (f taskFilter) match(task *Task) bool {
+	var ok bool
+	switch f.field {
+	case "text":
+		ok = f.text.matchWords(splitWords(task.Title)) || f.text.matchWords(splitWords(task.Comment))
+	case "title":
+		ok = f.text.matchWords(splitWords(task.Title))
+	case "comment":
+		ok = f.text.matchWords(splitWords(task.Comment))
+	case "before":
+		ok = task.Date < f.value
+	case "after":
+		ok = task.Date > f.value
+	case "date":
+		ok = task.Date == f.value
+	case "repeat":
+		if f.value == "rrule" {
+			ok = strings.HasPrefix(strings.ToUpper(task.Repeat), "RRULE:")
+		} else {
+			ok = task.Repeat == f.value || strings.HasPrefix(task.Repeat, f.value+" ")
+		}
+	case "has":
+		switch f.value {
+		case "comment":
+			ok = task.Comment != ""
+		case "repeat":
+			ok = task.Repeat != ""
+		case "time":
+			ok = task.Time != ""
+		}
+	case "project":
+		ok = projectID(task) == parseID(f.value)
+	case "blocked":
+		ok = len(task.BlockedBy) > 0
+	case "tag":
+		ok = slices.Contains(task.Tags, f.value)
+	case "priority":
+		switch f.value {
+		case "in":
+			ok = slices.Contains(f.numbers, task.Priority)
+		case "=":
+			ok = task.Priority == f.numbers[0]
+		case ">":
+			ok = task.Priority > f.numbers[0]
+		case ">=":
+			ok = task.Priority >= f.numbers[0]
+		case "<":
+			ok = task.Priority < f.numbers[0]
+		case "<=":
+			ok = task.Priority <= f.numbers[0]
+		}
+	}
+	return ok != f.negate
+}
+
+// optionFilters возвращает условия для тегов, приоритетов, проекта и блокировки из параметров выборки
+func optionFilters(opts TasksOptions) []taskFilter {
+	filters := make([]taskFilter, 0, len(opts.Tags)+1)
+	for _, tag := range opts.Tags {
+		filters = append(filters, taskFilter{field: "tag", value: tag})
+	}
+	if len(opts.Priorities) > 0 {
+		filters = append(filters, taskFilter{field: "priority", value: "in", numbers: opts.Priorities})
+	}
+	if opts.ProjectID != "" {
+		filters = append(filters, taskFilter{field: "project", value: opts.ProjectID})
+	}
+	if opts.Blocked != nil {
+		filters = append(filters, taskFilter{field: "blocked", negate: !*opts.Blocked})
+	}
+	return filters
+}
+
+// match проверяет все условия запроса, кроме полнотекстовой части
+func (q *taskQuery) match(task *Task) bool {
+	for _, f := range q.filters {
+		if !f.match(task) {
+			return false
+		}
+	}
+	return true
+}
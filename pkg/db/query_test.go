@@ -0,0 +1,60 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTaskQuery(t *testing.T) {
+	for _, v := range []struct {
+		query string
+		where string
+		args  []any
+		text  string
+	}{
+		{"", "", nil, ""},
+		{"отчёт", "", nil, `"отчёт" *`},
+		{"02.01.2026", "date = ?", []any{"20260102"}, ""},
+		{"title:report before:20261101 after:01.10.2026",
+			"id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?) AND date < ? AND date > ?",
+			[]any{`title : "report"`, "20261101", "20261001"}, ""},
+		{`comment:"горячей водой" -has:repeat`,
+			"id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?) AND NOT (repeat != '')",
+			[]any{`comment : "горячей водой"`}, ""},
+		{"repeat:W звонок -мама", "(repeat = ? OR repeat LIKE ?) AND NOT (id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?))",
+			[]any{"w", "w %", `"мама"`}, `"звонок" *`},
+		{"repeat:rrule has:time", "UPPER(repeat) LIKE ? AND time != ''", []any{"RRULE:%"}, ""},
+		{"re:meeting http://host/x 10:30", "", nil, `("re meeting" AND "http host x" AND "10 30" *)`},
+		{"отчёт -owner:me", "NOT (id IN (SELECT rowid FROM scheduler_fts WHERE scheduler_fts MATCH ?))", []any{`"owner me"`}, `"отчёт" *`},
+		{"priority:>=2 -priority:3", "priority >= ? AND NOT (priority = ?)", []any{2, 3}, ""},
+		{"tag:#Work -tag:дом", "id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.name = ?) AND " +
+			"NOT (id IN (SELECT task_tags.task_id FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE tags.name = ?))",
+			[]any{"work", "дом"}, ""},
+	} {
+		q, err := parseTaskQuery(v.query)
+		if !assert.NoError(t, err, v.query) {
+			continue
+		}
+		var where []string
+		var args []any
+		for _, f := range q.filters {
+			cond, condArgs := f.sql(sqliteDialect)
+			where = append(where, cond)
+			args = append(args, condArgs...)
+		}
+		assert.Equal(t, v.where, strings.Join(where, " AND "), v.query)
+		assert.Equal(t, v.args, args, v.query)
+		text := ""
+		if q.text != nil {
+			text = q.text.fts5()
+		}
+		assert.Equal(t, v.text, text, v.query)
+	}
+
+	for _, query := range []string{"priority:4", "priority:high", "before:2026", "repeat:hourly", "has:title", "title:", "title:(", "after:31.02.2026"} {
+		_, err := parseTaskQuery(query)
+		assert.ErrorIs(t, err, ErrBadSearch, query)
+	}
+}
@@ -0,0 +1,474 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// ErrBadSearch — поисковый запрос записан с ошибкой
+var ErrBadSearch = errors.New("invalid search query")
+
+const (
+	// SnippetStart и SnippetEnd обрамляют найденные слова во фрагменте задачи.
+	// Остальной текст фрагмента экранируется как HTML.
+	SnippetStart = "<mark>"
+	SnippetEnd   = "</mark>"
+
+	// snippetOpen и snippetClose — метки совпадений, которые вставляет база данных.
+	// Фрагмент экранируется уже после выборки, поэтому в SQL нельзя сразу подставить SnippetStart и SnippetEnd.
+	snippetOpen  = "\x02"
+	snippetClose = "\x03"
+)
+
+// snippetMarkers заменяет метки совпадений на SnippetStart и SnippetEnd
+var snippetMarkers = strings.NewReplacer(snippetOpen, SnippetStart, snippetClose, SnippetEnd)
+
+// snippetHTML превращает фрагмент из базы данных в HTML: экранирует текст задачи и расставляет выделение.
+// Если управляющие символы меток есть в самом тексте, они тоже станут тегами <mark>, но не другой разметкой.
+func snippetHTML(snippet string) string {
+	return snippetMarkers.Replace(html.EscapeString(snippet))
+}
+
+type searchOp int
+
+const (
+	searchTerm searchOp = iota
+	searchAnd
+	searchOr
+	searchNot
+)
+
+// searchNode — узел разобранного поискового запроса
+type searchNode struct {
+	op searchOp
+	// words — слова термина в нижнем регистре; несколько слов ищутся как фраза
+	words []string
+	// prefix — последнее слово термина ищется как начало слова
+	prefix   bool
+	children []*searchNode
+}
+
+// splitWords разбивает текст на слова из букв и цифр в нижнем регистре
+func splitWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// searchToken — лексема запроса: скобка, оператор, слово или фраза в кавычках
+type searchToken struct {
+	text   string
+	quoted bool
+}
+
+func lexSearch(s string) []searchToken {
+	var tokens []searchToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')':
+			tokens = append(tokens, searchToken{text: string(r)})
+			i++
+		case r == '"':
+			// Незакрытая кавычка продолжается до конца запроса
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, searchToken{text: string(runes[i+1 : min(end, len(runes))]), quoted: true})
+			i = end + 1
+			if i < len(runes) && runes[i] == '*' {
+				tokens[len(tokens)-1].text += "*"
+				i++
+			}
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune(`()"`, runes[end]) {
+				end++
+			}
+			tokens = append(tokens, searchToken{text: string(runes[i:end])})
+			i = end
+		}
+	}
+	return tokens
+}
+
+// searchParser разбирает запрос по грамматике:
+//
+//	or    = and { "OR" and }
+//	and   = unary { [ "AND" ] unary }
+//	unary = "NOT" unary | "(" or ")" | слово[*] | "фраза"[*]
+type searchParser struct {
+	tokens []searchToken
+	pos    int
+	// last — термин из последнего слова запроса без кавычек, если оно не исключено через NOT
+	last *searchNode
+}
+
+func (p *searchParser) peek() (searchToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return searchToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *searchParser) isOperator(op string) bool {
+	t, ok := p.peek()
+	return ok && !t.quoted && t.text == op
+}
+
+func (p *searchParser) parseOr() (*searchNode, error) {
+	node := &searchNode{op: searchOr}
+	for {
+		child, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+		if !p.isOperator("OR") {
+			break
+		}
+		p.pos++
+	}
+	return node, nil
+}
+
+func (p *searchParser) parseAnd() (*searchNode, error) {
+	node := &searchNode{op: searchAnd}
+	for {
+		child, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		node.children = append(node.children, child)
+
+		if p.isOperator("AND") {
+			p.pos++
+			continue
+		}
+		if _, ok := p.peek(); !ok || p.isOperator("OR") || p.isOperator(")") {
+			break
+		}
+	}
+	return node, nil
+}
+
+func (p *searchParser) parseUnary() (*searchNode, error) {
+	t, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: unexpected end of query", ErrBadSearch)
+	}
+	p.pos++
+
+	if !t.quoted {
+		switch t.text {
+		case "NOT":
+			child, err := p.parseUnary()
+			if err != nil {
+				return nil, err
+			}
+			if p.last == child {
+				p.last = nil
+			}
+			return &searchNode{op: searchNot, children: []*searchNode{child}}, nil
+		case "(":
+			node, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if !p.isOperator(")") {
+				return nil, fmt.Errorf("%w: missing closing parenthesis", ErrBadSearch)
+			}
+			p.pos++
+			return node, nil
+		case ")", "AND", "OR":
+			return nil, fmt.Errorf("%w: unexpected %q", ErrBadSearch, t.text)
+		}
+	}
+
+	prefix := strings.HasSuffix(t.text, "*")
+	node := &searchNode{op: searchTerm, words: splitWords(t.text), prefix: prefix}
+	if !t.quoted && p.pos == len(p.tokens) {
+		p.last = node
+	}
+	return node, nil
+}
+
+// simplify убирает термины без слов и лишние уровни AND и OR из одного элемента
+func (n *searchNode) simplify() *searchNode {
+	switch n.op {
+	case searchTerm:
+		if len(n.words) == 0 {
+			return nil
+		}
+	case searchNot:
+		child := n.children[0].simplify()
+		if child == nil {
+			return nil
+		}
+		n.children[0] = child
+	case searchAnd, searchOr:
+		children := n.children[:0]
+		for _, c := range n.children {
+			if c = c.simplify(); c != nil {
+				children = append(children, c)
+			}
+		}
+		n.children = children
+		switch len(children) {
+		case 0:
+			return nil
+		case 1:
+			return children[0]
+		}
+	}
+	return n
+}
+
+// check проверяет, что исключения NOT стоят рядом с тем, что ищется:
+// запрос вида «NOT слово» или «a OR NOT b» нельзя выполнить по индексу
+func (n *searchNode) check() error {
+	switch n.op {
+	case searchNot:
+		return fmt.Errorf("%w: NOT must follow a search term", ErrBadSearch)
+	case searchAnd:
+		positive := false
+		for _, c := range n.children {
+			if c.op == searchNot {
+				if c.children[0].op == searchNot {
+					return fmt.Errorf("%w: NOT must follow a search term", ErrBadSearch)
+				}
+				if err := c.children[0].check(); err != nil {
+					return err
+				}
+				continue
+			}
+			positive = true
+			if err := c.check(); err != nil {
+				return err
+			}
+		}
+		if !positive {
+			return fmt.Errorf("%w: NOT must follow a search term", ErrBadSearch)
+		}
+	case searchOr:
+		for _, c := range n.children {
+			if err := c.check(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// parseSearch разбирает поисковый запрос: слова, фразы в кавычках, префиксы со звёздочкой,
+// операторы AND, OR, NOT и скобки. Слова без оператора между ними объединяются через AND.
+func parseSearch(s string) (*searchNode, error) {
+	return (&searchParser{tokens: lexSearch(s)}).parse()
+}
+
+// parseTextSearch разбирает слова запроса к списку задач так же, как parseSearch, но последнее
+// слово без кавычек ищется как начало слова: «meet» находит «meeting», пока слово не дописано
+func parseTextSearch(s string) (*searchNode, error) {
+	p := &searchParser{tokens: lexSearch(s)}
+	node, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if p.last != nil {
+		p.last.prefix = true
+	}
+	return node, nil
+}
+
+func (p *searchParser) parse() (*searchNode, error) {
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrBadSearch, p.tokens[p.pos].text)
+	}
+
+	node = node.simplify()
+	if node == nil {
+		return nil, fmt.Errorf("%w: no words to search for", ErrBadSearch)
+	}
+	if err := node.check(); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// fts5 переводит запрос в синтаксис MATCH для SQLite FTS5
+func (n *searchNode) fts5() string {
+	switch n.op {
+	case searchTerm:
+		s := `"` + strings.Join(n.words, " ") + `"`
+		if n.prefix {
+			s += " *"
+		}
+		return s
+	case searchOr:
+		parts := make([]string, 0, len(n.children))
+		for _, c := range n.children {
+			parts = append(parts, c.fts5())
+		}
+		return "(" + strings.Join(parts, " OR ") + ")"
+	}
+
+	// В FTS5 оператор NOT бинарный: исключения идут после всех искомых терминов
+	var positive, negative []string
+	for _, c := range n.children {
+		if c.op == searchNot {
+			negative = append(negative, " NOT "+c.children[0].fts5())
+		} else {
+			positive = append(positive, c.fts5())
+		}
+	}
+	return "(" + strings.Join(positive, " AND ") + strings.Join(negative, "") + ")"
+}
+
+// tsquery переводит запрос в синтаксис to_tsquery для PostgreSQL
+func (n *searchNode) tsquery() string {
+	switch n.op {
+	case searchTerm:
+		words := make([]string, len(n.words))
+		for i, w := range n.words {
+			words[i] = "'" + w + "'"
+		}
+		if n.prefix {
+			words[len(words)-1] += ":*"
+		}
+		return "(" + strings.Join(words, " <-> ") + ")"
+	case searchNot:
+		return "!" + n.children[0].tsquery()
+	}
+
+	sep := " & "
+	if n.op == searchOr {
+		sep = " | "
+	}
+	parts := make([]string, 0, len(n.children))
+	for _, c := range n.children {
+		parts = append(parts, c.tsquery())
+	}
+	return "(" + strings.Join(parts, sep) + ")"
+}
+
+// termAt проверяет, начинается ли с i-го слова текста фраза термина
+func (n *searchNode) termAt(words []string, i int) bool {
+	if i+len(n.words) > len(words) {
+		return false
+	}
+	for j, w := range n.words {
+		if j == len(n.words)-1 && n.prefix {
+			if !strings.HasPrefix(words[i+j], w) {
+				return false
+			}
+		} else if words[i+j] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// matchWords вычисляет запрос на словах текста
+func (n *searchNode) matchWords(words []string) bool {
+	switch n.op {
+	case searchTerm:
+		for i := range words {
+			if n.termAt(words, i) {
+				return true
+			}
+		}
+		return false
+	case searchNot:
+		return !n.children[0].matchWords(words)
+	case searchAnd:
+		for _, c := range n.children {
+			if !c.matchWords(words) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, c := range n.children {
+		if c.matchWords(words) {
+			return true
+		}
+	}
+	return false
+}
+
+// terms возвращает искомые термины запроса, кроме исключённых через NOT
+func (n *searchNode) terms() []*searchNode {
+	switch n.op {
+	case searchTerm:
+		return []*searchNode{n}
+	case searchNot:
+		return nil
+	}
+	var terms []*searchNode
+	for _, c := range n.children {
+		terms = append(terms, c.terms()...)
+	}
+	return terms
+}
+
+// hits считает вхождения искомых терминов в текст
+func (n *searchNode) hits(text string) int {
+	words := splitWords(text)
+	count := 0
+	for _, t := range n.terms() {
+		for i := range words {
+			if t.termAt(words, i) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// highlight выделяет в тексте слова, совпавшие с искомыми терминами
+func (n *searchNode) highlight(text string) string {
+	var b strings.Builder
+	terms := n.terms()
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		if !unicode.IsLetter(runes[i]) && !unicode.IsDigit(runes[i]) {
+			b.WriteString(html.EscapeString(string(runes[i])))
+			i++
+			continue
+		}
+		end := i
+		for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end])) {
+			end++
+		}
+		word := string(runes[i:end])
+		if matchesAnyWord(terms, strings.ToLower(word)) {
+			b.WriteString(SnippetStart + html.EscapeString(word) + SnippetEnd)
+		} else {
+			b.WriteString(word)
+		}
+		i = end
+	}
+	return b.String()
+}
+
+func matchesAnyWord(terms []*searchNode, word string) bool {
+	for _, t := range terms {
+		for j, w := range t.words {
+			if word == w || (t.prefix && j == len(t.words)-1 && strings.HasPrefix(word, w)) {
+				return true
+			}
+		}
+	}
+	return false
+}
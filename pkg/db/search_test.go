@@ -0,0 +1,68 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSearch(t *testing.T) {
+	for _, v := range []struct {
+		query   string
+		fts5    string
+		tsquery string
+	}{
+		{"УК", `"ук"`, `('ук')`},
+		{"молоко хлеб", `("молоко" AND "хлеб")`, `(('молоко') & ('хлеб'))`},
+		{`"горячей водой" моло*`, `("горячей водой" AND "моло" *)`, `(('горячей' <-> 'водой') & ('моло':*))`},
+		{"a OR b c", `("a" OR ("b" AND "c"))`, `(('a') | (('b') & ('c')))`},
+		{"NOT a b", `("b" NOT "a")`, `(!('a') & ('b'))`},
+		{"a NOT (b OR c)", `("a" NOT ("b" OR "c"))`, `(('a') & !(('b') | ('c')))`},
+		{"18:00", `"18 00"`, `('18' <-> '00')`},
+		{`"незакрытая фраза`, `"незакрытая фраза"`, `('незакрытая' <-> 'фраза')`},
+	} {
+		node, err := parseSearch(v.query)
+		if !assert.NoError(t, err, v.query) {
+			continue
+		}
+		assert.Equal(t, v.fts5, node.fts5(), v.query)
+		assert.Equal(t, v.tsquery, node.tsquery(), v.query)
+	}
+
+	for _, query := range []string{"", "NOT a", "a OR NOT b", "(a", "a)", "AND", "a AND", "!!!", "NOT NOT a b"} {
+		_, err := parseSearch(query)
+		assert.ErrorIs(t, err, ErrBadSearch, query)
+	}
+}
+
+// В свободном тексте последнее слово без кавычек ищется как начало слова
+func TestParseTextSearch(t *testing.T) {
+	for _, v := range []struct {
+		query string
+		fts5  string
+	}{
+		{"meet", `"meet" *`},
+		{"молоко хлеб", `("молоко" AND "хлеб" *)`},
+		{"моло*", `"моло" *`},
+		{`"горячей водой"`, `"горячей водой"`},
+		{"a NOT b", `("a" NOT "b")`},
+		{"(a OR b)", `("a" OR "b")`},
+		{"10:30", `"10 30" *`},
+	} {
+		node, err := parseTextSearch(v.query)
+		if assert.NoError(t, err, v.query) {
+			assert.Equal(t, v.fts5, node.fts5(), v.query)
+		}
+	}
+}
+
+// Фрагмент — HTML: текст задачи экранируется, а выделяются только совпадения
+func TestSnippetEscaping(t *testing.T) {
+	assert.Equal(t, "<mark>5</mark> &gt; 3 &amp;&amp; &lt;img src=x&gt;",
+		snippetHTML(snippetOpen+"5"+snippetClose+" > 3 && <img src=x>"))
+
+	n, err := parseSearch("img")
+	assert.NoError(t, err)
+	assert.Equal(t, "&lt;<mark>img</mark> src=x onerror=alert(1)&gt; &amp; &#39;",
+		n.highlight("<img src=x onerror=alert(1)> & '"))
+}
@@ -0,0 +1,146 @@
+package db
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ErrBadSort — порядок сортировки записан с ошибкой или содержит неизвестное поле
+var ErrBadSort = errors.New("invalid sort")
+
+// sortFields — поля, по которым разрешено сортировать список задач, и их столбцы.
+// В текст запроса попадают только столбцы из этого списка.
+var sortFields = map[string][]string{
+	"date":     {"date", "time"},
+	"title":    {"title"},
+	"created":  {"created_at"},
+	"updated":  {"updated_at"},
+	"priority": {"priority"},
+}
+
+// defaultSort — порядок по умолчанию: по дате, на одну дату сначала важные задачи, затем по времени
+var defaultSort = []sortKey{{column: "date"}, {column: "priority", desc: true}, {column: "time"}}
+
+// sortKey — столбец сортировки и направление
+type sortKey struct {
+	column string
+	desc   bool
+}
+
+// parseSort разбирает порядок вида "-created,title": поля через запятую, минус — по убыванию.
+// Пустой порядок — defaultSort. Задачи с равными ключами всегда упорядочены по id,
+// поэтому порядок однозначен и пригоден для постраничной выборки.
+func parseSort(s string) ([]sortKey, error) {
+	if s == "" {
+		return defaultSort, nil
+	}
+
+	var keys []sortKey
+	used := make(map[string]bool)
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		desc := strings.HasPrefix(field, "-")
+		field = strings.TrimPrefix(strings.TrimPrefix(field, "-"), "+")
+		columns, ok := sortFields[field]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrBadSort, field)
+		}
+		if used[field] {
+			return nil, fmt.Errorf("%w: duplicate field %q", ErrBadSort, field)
+		}
+		used[field] = true
+		for _, column := range columns {
+			keys = append(keys, sortKey{column: column, desc: desc})
+		}
+	}
+	return keys, nil
+}
+
+// orderBy возвращает список для ORDER BY
+func orderBy(keys []sortKey) string {
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(k.column)
+		if k.desc {
+			b.WriteString(" DESC")
+		}
+		b.WriteString(", ")
+	}
+	b.WriteString("id")
+	return b.String()
+}
+
+// afterCondition возвращает условие WHERE для задач, которые идут в порядке keys после курсора:
+// (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... OR (все ключи равны AND id > ?)
+func afterCondition(keys []sortKey, c *taskCursor) (string, []any) {
+	var parts []string
+	var args []any
+	var equal []string
+	var equalArgs []any
+	for i, k := range keys {
+		op := " > ?"
+		if k.desc {
+			op = " < ?"
+		}
+		var value any = c.Keys[i]
+		if sortColumns[k.column].numeric {
+			value, _ = strconv.Atoi(c.Keys[i])
+		}
+		parts = append(parts, "("+strings.Join(append(equal, k.column+op), " AND ")+")")
+		args = append(append(args, equalArgs...), value)
+		equal = append(equal, k.column+" = ?")
+		equalArgs = append(equalArgs, value)
+	}
+	parts = append(parts, "("+strings.Join(append(equal, "id > ?"), " AND ")+")")
+	args = append(append(args, equalArgs...), c.ID)
+	return "(" + strings.Join(parts, " OR ") + ")", args
+}
+
+// sortColumn — столбец сортировки в задаче. В курсоре значения хранятся строками.
+type sortColumn struct {
+	get     func(*Task) string
+	set     func(*Task, string)
+	compare func(a, b *Task) int
+	// numeric — значение из курсора передаётся в запрос числом
+	numeric bool
+}
+
+// textColumn описывает текстовый столбец сортировки
+func textColumn(field func(*Task) *string) sortColumn {
+	return sortColumn{
+		get:     func(t *Task) string { return *field(t) },
+		set:     func(t *Task, v string) { *field(t) = v },
+		compare: func(a, b *Task) int { return strings.Compare(*field(a), *field(b)) },
+	}
+}
+
+// sortColumns — столбцы сортировки в задаче
+var sortColumns = map[string]sortColumn{
+	"date":       textColumn(func(t *Task) *string { return &t.Date }),
+	"time":       textColumn(func(t *Task) *string { return &t.Time }),
+	"title":      textColumn(func(t *Task) *string { return &t.Title }),
+	"created_at": textColumn(func(t *Task) *string { return &t.CreatedAt }),
+	"updated_at": textColumn(func(t *Task) *string { return &t.UpdatedAt }),
+	"priority": {
+		get:     func(t *Task) string { return strconv.Itoa(t.Priority) },
+		set:     func(t *Task, v string) { t.Priority, _ = strconv.Atoi(v) },
+		compare: func(a, b *Task) int { return cmp.Compare(a.Priority, b.Priority) },
+		numeric: true,
+	},
+}
+
+// compareTasks сравнивает задачи в порядке keys, как ORDER BY orderBy(keys)
+func compareTasks(keys []sortKey, a, b *Task) int {
+	for _, k := range keys {
+		if c := sortColumns[k.column].compare(a, b); c != 0 {
+			if k.desc {
+				return -c
+			}
+			return c
+		}
+	}
+	return cmp.Compare(taskID(a), taskID(b))
+}
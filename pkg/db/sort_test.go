@@ -0,0 +1,82 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	for _, v := range []struct {
+		sort  string
+		order string
+	}{
+		{"", "date, priority DESC, time, id"},
+		{"-priority,date", "priority DESC, date, time, id"},
+		{"-date", "date DESC, time DESC, id"},
+		{"title,-created", "title, created_at DESC, id"},
+		{" +updated , date", "updated_at, date, time, id"},
+	} {
+		keys, err := parseSort(v.sort)
+		if assert.NoError(t, err, v.sort) {
+			assert.Equal(t, v.order, orderBy(keys), v.sort)
+		}
+	}
+
+	for _, sort := range []string{"importance", "title,title", "date;DROP TABLE scheduler", ",", "id"} {
+		_, err := parseSort(sort)
+		assert.ErrorIs(t, err, ErrBadSort, sort)
+	}
+}
+
+func TestSortCursor(t *testing.T) {
+	keys, err := parseSort("-title,date")
+	if !assert.NoError(t, err) {
+		return
+	}
+	last := &Task{ID: "7", Title: "Б", Date: "20260102", Time: "10:00"}
+	cursor, err := decodeCursor(nextCursor([]*Task{last}, "-title,date", keys, false, 0), "-title,date", keys, false)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cond, args := afterCondition(keys, cursor)
+	assert.Equal(t, "((title < ?) OR (title = ? AND date > ?) OR (title = ? AND date = ? AND time > ?) OR "+
+		"(title = ? AND date = ? AND time = ? AND id > ?))", cond)
+	assert.Equal(t, []any{"Б", "Б", "20260102", "Б", "20260102", "10:00", "Б", "20260102", "10:00", int64(7)}, args)
+
+	assert.True(t, cursor.after(keys, &Task{ID: "1", Title: "А"}))
+	assert.True(t, cursor.after(keys, &Task{ID: "8", Title: "Б", Date: "20260102", Time: "10:00"}))
+	assert.False(t, cursor.after(keys, &Task{ID: "6", Title: "Б", Date: "20260102", Time: "10:00"}))
+	assert.False(t, cursor.after(keys, &Task{ID: "9", Title: "В"}))
+
+	// Курсор другого порядка или поиска по релевантности не подходит
+	_, err = decodeCursor(cursor.encode(), "title", keys[:1], false)
+	assert.ErrorIs(t, err, ErrBadCursor)
+	_, err = decodeCursor(cursor.encode(), "", nil, true)
+	assert.ErrorIs(t, err, ErrBadCursor)
+}
+
+func TestNewestCursor(t *testing.T) {
+	cursor, err := decodeNewestCursor(newestCursor(completionsList, "2030-01-01T10:00:00Z", 7), completionsList)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cond, args := cursor.olderCondition("completed_at")
+	assert.Equal(t, "(completed_at < ? OR (completed_at = ? AND id < ?))", cond)
+	assert.Equal(t, []any{"2030-01-01T10:00:00Z", "2030-01-01T10:00:00Z", int64(7)}, args)
+
+	assert.True(t, cursor.older("2030-01-01T09:00:00Z", 9))
+	assert.True(t, cursor.older("2030-01-01T10:00:00Z", 6))
+	assert.False(t, cursor.older("2030-01-01T10:00:00Z", 8))
+	assert.False(t, cursor.older("2030-01-01T11:00:00Z", 1))
+
+	// Курсор другого списка или списка задач не подходит
+	_, err = decodeNewestCursor(cursor.encode(), "trash")
+	assert.ErrorIs(t, err, ErrBadCursor)
+	_, err = decodeNewestCursor((&taskCursor{Offset: 10}).encode(), completionsList)
+	assert.ErrorIs(t, err, ErrBadCursor)
+	_, err = decodeNewestCursor("abc", completionsList)
+	assert.ErrorIs(t, err, ErrBadCursor)
+}
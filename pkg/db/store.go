@@ -0,0 +1,103 @@
+package db
+
+import "time"
+
+// TaskStore — хранилище задач и связанных с ними данных.
+// Обработчики API работают только через этот интерфейс.
+type TaskStore interface {
+	AddTask(task *Task) (int64, error)
+	// GetTask возвращает задачу, если она не находится в корзине
+	GetTask(id string) (*Task, error)
+	UpdateTask(task *Task) error
+	// UpdateTaskDate переносит задачу на новую дату и сохраняет оставшееся число повторений
+	UpdateTaskDate(id string, date string, remaining int) error
+	// MarkTaskDone отмечает задачу выполненной, не удаляя её
+	MarkTaskDone(id string) error
+	// DeleteTask перемещает задачу в корзину
+	DeleteTask(id string) error
+	// RestoreTask записывает задачу целиком с её прежним идентификатором
+	RestoreTask(task *Task) error
+	// Tasks возвращает страницу активных задач, отобранных по opts
+	Tasks(opts TasksOptions) (*TaskPage, error)
+
+	// TrashedTasks возвращает страницу корзины; cursor — TaskPage.NextCursor предыдущей страницы
+	TrashedTasks(limit int, cursor string) (*TaskPage, error)
+	RestoreTrashedTask(id string) error
+	// PurgeTask удаляет задачу окончательно, в том числе из корзины
+	PurgeTask(id string) error
+	// PurgeTrashedTask окончательно удаляет задачу, только если она в корзине
+	PurgeTrashedTask(id string) error
+	// PurgeTrash окончательно удаляет задачи, попавшие в корзину раньше before
+	PurgeTrash(before time.Time) (int64, error)
+	EmptyTrash() (int64, error)
+
+	// Tags возвращает теги активных задач с числом задач
+	Tags() ([]*Tag, error)
+	// MergeTags переносит задачи с тегов names на тег into и удаляет теги names
+	MergeTags(names []string, into string) error
+
+	// AddTaskItem добавляет пункт в конец чек-листа задачи
+	AddTaskItem(item *TaskItem) (int64, error)
+	GetTaskItem(id string) (*TaskItem, error)
+	// UpdateTaskItem изменяет пункт; Position больше 0 переставляет его на эту позицию
+	UpdateTaskItem(item *TaskItem) error
+	DeleteTaskItem(id string) error
+	// TaskItems возвращает чек-лист задачи по порядку
+	TaskItems(taskID string) ([]*TaskItem, error)
+	// ResetTaskItems снимает отметки со всех пунктов чек-листа задачи
+	ResetTaskItems(taskID string) error
+
+	AddProject(p *Project) (int64, error)
+	// GetProject возвращает проект вместе с числом активных задач
+	GetProject(id string) (*Project, error)
+	UpdateProject(p *Project) error
+	// DeleteProject удаляет проект; его задачи переходят в проект moveTo или в корзину
+	DeleteProject(id, moveTo string, trashTasks bool) error
+	// Projects возвращает проекты с числом активных задач; архивные — только если archived
+	Projects(archived bool) ([]*Project, error)
+
+	AddHoliday(h *Holiday) error
+	DeleteHoliday(date string) error
+	Holidays(from, to string) ([]*Holiday, error)
+	HolidaySet() (map[string]bool, error)
+
+	AddCompletion(c *Completion) (int64, error)
+	DeleteCompletion(id int64) error
+	Completions(opts CompletionsOptions) (*CompletionPage, error)
+
+	// InTx выполняет fn в транзакции: при ошибке fn все изменения, сделанные через tx, отменяются.
+	// Вызов InTx внутри fn отменяет при ошибке только свои изменения.
+	InTx(fn func(tx TaskStore) error) error
+
+	SchemaVersion() (int, error)
+	Close() error
+}
+
+// TasksOptions — параметры выборки списка задач
+type TasksOptions struct {
+	Limit int
+	// Search — поисковый запрос (см. parseTaskQuery)
+	Search string
+	// From и To — границы даты задачи включительно в формате 20060102; пустая граница не ограничивает
+	From, To string
+	// Tags — задача должна иметь все перечисленные теги
+	Tags []string
+	// Priorities — допустимые приоритеты задачи; пусто — любые
+	Priorities []int
+	// ProjectID — проект задач; "0" — задачи без проекта, пусто — задачи всех проектов не из архива
+	ProjectID string
+	// Sort — порядок сортировки, например "-created,title" (см. parseSort);
+	// пусто — по релевантности при поиске по словам, иначе по дате
+	Sort string
+	// Cursor — курсор страницы из TaskPage.NextCursor; пусто для первой страницы
+	Cursor string
+	// Blocked — только заблокированные (true) или только доступные для выполнения (false) задачи; nil — любые
+	Blocked *bool
+	// Total — посчитать число задач по запросу без учёта страниц
+	Total bool
+}
+
+var (
+	_ TaskStore = (*SQLStore)(nil)
+	_ TaskStore = (*MemoryStore)(nil)
+)
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// ErrTagNotFound — тега нет ни у одной задачи
+var ErrTagNotFound = errors.New("tag not found")
+
+// Tag — тег и число активных задач с ним
+type Tag struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// setTaskTags заменяет теги задачи; недостающие теги создаются, неиспользуемые удаляются
+func (s *SQLStore) setTaskTags(taskID int64, tags []string) error {
+	if _, err := s.exec(`DELETE FROM task_tags WHERE task_id = ?`, taskID); err != nil {
+		return err
+	}
+
+	for _, name := range tags {
+		tagID, err := s.ensureTag(name)
+		if err != nil {
+			return err
+		}
+		_, err = s.exec(`INSERT INTO task_tags (task_id, tag_id) VALUES (?, ?) ON CONFLICT DO NOTHING`, taskID, tagID)
+		if err != nil {
+			return err
+		}
+	}
+	return s.pruneTags()
+}
+
+// ensureTag возвращает идентификатор тега, создавая тег при необходимости
+func (s *SQLStore) ensureTag(name string) (int64, error) {
+	if _, err := s.exec(`INSERT INTO tags (name) VALUES (?) ON CONFLICT (name) DO NOTHING`, name); err != nil {
+		return 0, err
+	}
+	var id int64
+	err := s.queryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&id)
+	return id, err
+}
+
+// pruneTags удаляет теги, которые не назначены ни одной задаче
+func (s *SQLStore) pruneTags() error {
+	_, err := s.exec(`DELETE FROM tags WHERE NOT EXISTS (SELECT 1 FROM task_tags WHERE task_tags.tag_id = tags.id)`)
+	return err
+}
+
+// loadTags заполняет теги задач одним запросом
+func (s *SQLStore) loadTags(tasks []*Task) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*Task, len(tasks))
+	args := make([]any, 0, len(tasks))
+	for _, task := range tasks {
+		byID[taskID(task)] = task
+		args = append(args, taskID(task))
+	}
+
+	query := `SELECT task_tags.task_id, tags.name FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+		WHERE task_tags.task_id IN (?` + strings.Repeat(", ?", len(args)-1) + `) ORDER BY tags.name`
+	rows, err := s.query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return err
+		}
+		if task, ok := byID[id]; ok {
+			task.Tags = append(task.Tags, name)
+		}
+	}
+	return rows.Err()
+}
+
+// Tags возвращает теги активных задач с числом задач, по алфавиту
+func (s *SQLStore) Tags() ([]*Tag, error) {
+	tags := make([]*Tag, 0)
+
+	query := `SELECT tags.name, COUNT(*) FROM tags
+		JOIN task_tags ON task_tags.tag_id = tags.id
+		JOIN scheduler ON scheduler.id = task_tags.task_id
+		WHERE scheduler.done = 0 AND scheduler.deleted_at = ''
+		GROUP BY tags.name ORDER BY tags.name`
+	rows, err := s.query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Name, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// MergeTags переносит задачи с тегов names на тег into и удаляет теги names.
+// Переименование — объединение одного тега с новым именем.
+func (s *SQLStore) MergeTags(names []string, into string) error {
+	return s.inTx(func(tx *SQLStore) error {
+		intoID, err := tx.ensureTag(into)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range names {
+			if name == into {
+				continue
+			}
+			var id int64
+			err := tx.queryRow(`SELECT id FROM tags WHERE name = ?`, name).Scan(&id)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrTagNotFound
+			}
+			if err != nil {
+				return err
+			}
+			_, err = tx.exec(`INSERT INTO task_tags (task_id, tag_id)
+				SELECT task_id, CAST(? AS INTEGER) FROM task_tags WHERE tag_id = ? ON CONFLICT DO NOTHING`, intoID, id)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.exec(`DELETE FROM task_tags WHERE tag_id = ?`, id); err != nil {
+				return err
+			}
+		}
+		return tx.pruneTags()
+	})
+}
@@ -1,47 +1,214 @@
 package db
 
 import (
-	"database/sql"
 	"fmt"
 	"strconv"
 	"time"
 )
 
+// MaxPriority — наибольший приоритет задачи; 0 — обычная задача
+const MaxPriority = 3
+
 type Task struct {
-	ID      string `json:"id"`
-	Date    string `json:"date"`
-	Title   string `json:"title"`
-	Comment string `json:"comment"`
-	Repeat  string `json:"repeat"`
+	ID       string `json:"id"`
+	Date     string `json:"date"`
+	Title    string `json:"title"`
+	Comment  string `json:"comment"`
+	Repeat   string `json:"repeat"`
+	Time     string `json:"time,omitempty"`
+	Duration int    `json:"duration,omitempty"`
+	// Priority — важность задачи от 0 до MaxPriority; в списке на одну дату важные идут первыми
+	Priority int `json:"priority,omitempty"`
+	// Remaining — сколько повторений осталось, включая текущее; 0 — без ограничения
+	Remaining int `json:"remaining,omitempty"`
+	// Done — задача выполнена и сохранена вместо удаления
+	Done bool `json:"done,omitempty"`
+	// DeletedAt — время перемещения в корзину (UTC, RFC 3339); пусто для обычных задач
+	DeletedAt string `json:"deleted_at,omitempty"`
+	// CreatedAt и UpdatedAt — время создания и последнего изменения (UTC, RFC 3339);
+	// пусто у задач, созданных до появления этих полей
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	// ProjectID — проект задачи; пусто — задача без проекта
+	ProjectID string `json:"project_id,omitempty"`
+	// Tags — теги задачи по алфавиту. При изменении задачи nil оставляет теги прежними.
+	Tags []string `json:"tags,omitempty"`
+	// DependsOn — задачи, которые нужно выполнить раньше этой. При изменении задачи nil оставляет связи прежними.
+	DependsOn []string `json:"depends_on,omitempty"`
+	// BlockedBy — ещё не выполненные задачи из DependsOn; пока список не пуст, задача заблокирована
+	BlockedBy []string `json:"blocked_by,omitempty"`
+	// Blocks — задачи, которые зависят от этой; заполняется только для одной задачи (GetTask)
+	Blocks []string `json:"blocks,omitempty"`
+	// Items — чек-лист задачи; заполняется только для одной задачи (GetTask).
+	// При добавлении и восстановлении задачи пункты сохраняются вместе с ней, при изменении не трогаются.
+	Items []*TaskItem `json:"items,omitempty"`
+	// Progress — сколько пунктов чек-листа выполнено; nil, если чек-листа нет
+	Progress *Progress `json:"progress,omitempty"`
+	// Snippet — фрагмент текста с выделенными совпадениями; заполняется только при поиске
+	Snippet string `json:"snippet,omitempty"`
 }
 
-func AddTask(task *Task) (int64, error) {
-	var id int64
-	query := `INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`
-	res, err := DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat)
-	if err == nil {
-		id, err = res.LastInsertId()
+// taskColumns — столбцы задачи в порядке, который ожидает scanTask
+const taskColumns = `id, date, title, comment, repeat, time, duration, priority, project_id, remaining, done, deleted_at, created_at, updated_at`
+
+// rowScanner — общий интерфейс *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// parseID возвращает числовой идентификатор из строки; 0 — пусто или не число
+func parseID(s string) int64 {
+	id, _ := strconv.ParseInt(s, 10, 64)
+	return id
+}
+
+// taskID возвращает числовой идентификатор задачи
+func taskID(task *Task) int64 {
+	return parseID(task.ID)
+}
+
+func scanTask(row rowScanner) (*Task, error) {
+	var task Task
+	return &task, scanTaskInto(row, &task)
+}
+
+// scanFoundTask читает задачу вместе с фрагментом, найденным полнотекстовым поиском
+func scanFoundTask(row rowScanner) (*Task, error) {
+	var task Task
+	if err := scanTaskInto(row, &task, &task.Snippet); err != nil {
+		return nil, err
 	}
+	task.Snippet = snippetHTML(task.Snippet)
+	return &task, nil
+}
+
+// scanTaskInto читает столбцы taskColumns и дополнительные столбцы extra
+func scanTaskInto(row rowScanner, task *Task, extra ...any) error {
+	var id, project int64
+	err := row.Scan(append([]any{&id, &task.Date, &task.Title, &task.Comment, &task.Repeat, &task.Time, &task.Duration,
+		&task.Priority, &project, &task.Remaining, &task.Done, &task.DeletedAt, &task.CreatedAt, &task.UpdatedAt}, extra...)...)
+	if err != nil {
+		return err
+	}
+	task.ID = strconv.FormatInt(id, 10)
+	if project != 0 {
+		task.ProjectID = strconv.FormatInt(project, 10)
+	}
+	return nil
+}
+
+func (s *SQLStore) AddTask(task *Task) (int64, error) {
+	var id int64
+	err := s.inTx(func(tx *SQLStore) error {
+		now := time.Now().UTC().Format(time.RFC3339)
+		query := `INSERT INTO scheduler (date, title, comment, repeat, time, duration, priority, project_id, remaining, created_at, updated_at)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) RETURNING id`
+		err := tx.queryRow(query, task.Date, task.Title, task.Comment, task.Repeat, task.Time, task.Duration, task.Priority,
+			projectID(task), task.Remaining, now, now).Scan(&id)
+		if err != nil {
+			return err
+		}
+		if err := tx.setTaskTags(id, task.Tags); err != nil {
+			return err
+		}
+		if err := tx.setTaskDeps(id, task.DependsOn); err != nil {
+			return err
+		}
+		return tx.setTaskItems(id, task.Items)
+	})
 	return id, err
 }
 
-func GetTask(id string) (*Task, error) {
-	var task Task
-	var idInt int64
+// RestoreTask записывает задачу целиком с её прежним идентификатором:
+// вставляет удалённую строку или возвращает изменённой строке прежнее состояние
+func (s *SQLStore) RestoreTask(task *Task) error {
+	query := `INSERT INTO scheduler (id, date, title, comment, repeat, time, duration, priority, project_id, remaining, done, deleted_at,
+			created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET date = excluded.date, title = excluded.title, comment = excluded.comment,
+			repeat = excluded.repeat, time = excluded.time, duration = excluded.duration, priority = excluded.priority,
+			project_id = excluded.project_id,
+			remaining = excluded.remaining, done = excluded.done, deleted_at = excluded.deleted_at,
+			created_at = excluded.created_at, updated_at = excluded.updated_at`
+	return s.inTx(func(tx *SQLStore) error {
+		_, err := tx.exec(query, task.ID, task.Date, task.Title, task.Comment, task.Repeat,
+			task.Time, task.Duration, task.Priority, projectID(task), task.Remaining, boolInt(task.Done), task.DeletedAt,
+			task.CreatedAt, task.UpdatedAt)
+		if err != nil {
+			return err
+		}
+		if err := tx.setTaskTags(taskID(task), task.Tags); err != nil {
+			return err
+		}
+		if err := tx.restoreTaskDeps(task); err != nil {
+			return err
+		}
+		return tx.setTaskItems(taskID(task), task.Items)
+	})
+}
 
-	query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE id = ?`
-	err := DB.QueryRow(query, id).Scan(&idInt, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+// GetTask возвращает задачу вместе с тегами, зависимостями и чек-листом, если она не находится в корзине
+func (s *SQLStore) GetTask(id string) (*Task, error) {
+	query := `SELECT ` + taskColumns + ` FROM scheduler WHERE id = ? AND deleted_at = ''`
+	task, err := scanTask(s.queryRow(query, id))
 	if err != nil {
 		return nil, err
 	}
+	if err := s.loadTags([]*Task{task}); err != nil {
+		return nil, err
+	}
+	if err := s.loadDeps([]*Task{task}); err != nil {
+		return nil, err
+	}
+	if err := s.loadBlocks(task); err != nil {
+		return nil, err
+	}
+	if task.Items, err = s.TaskItems(task.ID); err != nil {
+		return nil, err
+	}
+	if len(task.Items) == 0 {
+		task.Items = nil
+	}
+	task.Progress = progress(task.Items)
+	return task, nil
+}
 
-	task.ID = strconv.FormatInt(idInt, 10)
-	return &task, nil
+// UpdateTask изменяет задачу; теги и зависимости заменяются, только если они не nil
+func (s *SQLStore) UpdateTask(task *Task) error {
+	return s.inTx(func(tx *SQLStore) error {
+		query := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ?, time = ?, duration = ?, priority = ?, project_id = ?,
+			remaining = ?, updated_at = ? WHERE id = ? AND deleted_at = ''`
+		res, err := tx.exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.Time, task.Duration, task.Priority,
+			projectID(task), task.Remaining, time.Now().UTC().Format(time.RFC3339), task.ID)
+		if err != nil {
+			return err
+		}
+
+		count, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return fmt.Errorf("incorrect id for updating task")
+		}
+
+		if task.Tags != nil {
+			if err := tx.setTaskTags(taskID(task), task.Tags); err != nil {
+				return err
+			}
+		}
+		if task.DependsOn == nil {
+			return nil
+		}
+		return tx.setTaskDeps(taskID(task), task.DependsOn)
+	})
 }
 
-func UpdateTask(task *Task) error {
-	query := `UPDATE scheduler SET date = ?, title = ?, comment = ?, repeat = ? WHERE id = ?`
-	res, err := DB.Exec(query, task.Date, task.Title, task.Comment, task.Repeat, task.ID)
+// UpdateTaskDate переносит задачу на новую дату и сохраняет оставшееся число повторений
+func (s *SQLStore) UpdateTaskDate(id string, date string, remaining int) error {
+	query := `UPDATE scheduler SET date = ?, remaining = ?, updated_at = ? WHERE id = ? AND deleted_at = ''`
+	res, err := s.exec(query, date, remaining, time.Now().UTC().Format(time.RFC3339), id)
 	if err != nil {
 		return err
 	}
@@ -52,15 +219,16 @@ func UpdateTask(task *Task) error {
 	}
 
 	if count == 0 {
-		return fmt.Errorf("incorrect id for updating task")
+		return fmt.Errorf("incorrect id for updating task date")
 	}
 
 	return nil
 }
 
-func UpdateTaskDate(id string, date string) error {
-	query := `UPDATE scheduler SET date = ? WHERE id = ?`
-	res, err := DB.Exec(query, date, id)
+// MarkTaskDone отмечает задачу выполненной, не удаляя её
+func (s *SQLStore) MarkTaskDone(id string) error {
+	query := `UPDATE scheduler SET done = 1, updated_at = ? WHERE id = ? AND deleted_at = ''`
+	res, err := s.exec(query, time.Now().UTC().Format(time.RFC3339), id)
 	if err != nil {
 		return err
 	}
@@ -71,15 +239,16 @@ func UpdateTaskDate(id string, date string) error {
 	}
 
 	if count == 0 {
-		return fmt.Errorf("incorrect id for updating task date")
+		return fmt.Errorf("incorrect id for marking task done")
 	}
 
 	return nil
 }
 
-func DeleteTask(id string) error {
-	query := `DELETE FROM scheduler WHERE id = ?`
-	res, err := DB.Exec(query, id)
+// DeleteTask перемещает задачу в корзину
+func (s *SQLStore) DeleteTask(id string) error {
+	query := `UPDATE scheduler SET deleted_at = ? WHERE id = ? AND deleted_at = ''`
+	res, err := s.exec(query, time.Now().UTC().Format(time.RFC3339), id)
 	if err != nil {
 		return err
 	}
@@ -96,51 +265,110 @@ func DeleteTask(id string) error {
 	return nil
 }
 
-func Tasks(limit int, search string) ([]*Task, error) {
-	tasks := make([]*Task, 0)
-
-	var rows *sql.Rows
-	var err error
-
-	if search == "" {
-		// Без поиска - все задачи
-		query := `SELECT id, date, title, comment, repeat FROM scheduler ORDER BY date LIMIT ?`
-		rows, err = DB.Query(query, limit)
-	} else {
-		// Проверяем, является ли search датой в формате 02.01.2006
-		t, parseErr := time.Parse("02.01.2006", search)
-		if parseErr == nil {
-			// Это дата - ищем по дате
-			dateStr := t.Format("20060102")
-			query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE date = ? ORDER BY date LIMIT ?`
-			rows, err = DB.Query(query, dateStr, limit)
-		} else {
-			// Это текст - ищем в заголовке и комментарии
-			searchPattern := "%" + search + "%"
-			query := `SELECT id, date, title, comment, repeat FROM scheduler WHERE title LIKE ? OR comment LIKE ? ORDER BY date LIMIT ?`
-			rows, err = DB.Query(query, searchPattern, searchPattern, limit)
+// Tasks возвращает страницу активных задач, отобранных запросом opts.Search (см. parseTaskQuery).
+// Задачи упорядочены по opts.Sort (см. parseSort); без него найденные по словам задачи
+// упорядочены по релевантности, остальные — по дате, приоритету, времени и id.
+func (s *SQLStore) Tasks(opts TasksOptions) (*TaskPage, error) {
+	q, err := parseTaskQuery(opts.Search)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parseSort(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	ranked := q.text != nil && opts.Sort == ""
+	cursor, err := decodeCursor(opts.Cursor, opts.Sort, keys, ranked)
+	if err != nil {
+		return nil, err
+	}
+
+	where := conditions{"done = 0", "deleted_at = ''"}
+	var args []any
+	// Задачи архивных проектов видны, только если проект выбран явно
+	if opts.ProjectID == "" {
+		where = append(where, "project_id NOT IN (SELECT id FROM projects WHERE archived = 1)")
+	}
+	// Границы периода выбираются по индексу idx_scheduler_date
+	if opts.From != "" {
+		where = append(where, "date >= ?")
+		args = append(args, opts.From)
+	}
+	if opts.To != "" {
+		where = append(where, "date <= ?")
+		args = append(args, opts.To)
+	}
+	for _, f := range append(optionFilters(opts), q.filters...) {
+		cond, condArgs := f.sql(s.dialect)
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	from, columns, order := "scheduler", taskColumns, orderBy(keys)
+	scan := scanTask
+	if q.text != nil {
+		// Слова ищем по индексу заголовков и комментариев
+		if s.dialect.searchWhere != "" {
+			where = append(where, s.dialect.searchWhere)
+		}
+		from = s.dialect.searchFrom
+		columns += ", " + s.dialect.searchSnippet
+		args = append([]any{s.dialect.searchExpr(q.text)}, args...)
+		scan = scanFoundTask
+	}
+	if ranked {
+		order = s.dialect.searchRank + ", " + order
+	}
+
+	page := &TaskPage{Tasks: make([]*Task, 0)}
+	if opts.Total {
+		err := s.queryRow(`SELECT COUNT(*) FROM `+from+where.sql(), args...).Scan(&page.Total)
+		if err != nil {
+			return nil, err
 		}
 	}
 
+	offset := 0
+	if cursor != nil && ranked {
+		offset = cursor.Offset
+	} else if cursor != nil {
+		cond, condArgs := afterCondition(keys, cursor)
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	// Лишняя задача показывает, есть ли следующая страница
+	query := `SELECT ` + columns + ` FROM ` + from + where.sql() + ` ORDER BY ` + order + ` LIMIT ? OFFSET ?`
+	rows, err := s.query(query, append(args, opts.Limit+1, offset)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		var task Task
-		var id int64
-		err := rows.Scan(&id, &task.Date, &task.Title, &task.Comment, &task.Repeat)
+		task, err := scan(rows)
 		if err != nil {
 			return nil, err
 		}
-		task.ID = strconv.FormatInt(id, 10)
-		tasks = append(tasks, &task)
+		page.Tasks = append(page.Tasks, task)
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
 
-	return tasks, nil
+	if len(page.Tasks) > opts.Limit {
+		page.Tasks = page.Tasks[:opts.Limit]
+		page.NextCursor = nextCursor(page.Tasks, opts.Sort, keys, ranked, offset)
+	}
+	if err := s.loadTags(page.Tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadDeps(page.Tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadProgress(page.Tasks); err != nil {
+		return nil, err
+	}
+	return page, nil
 }
@@ -0,0 +1,150 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// trashList — имя корзины в курсоре страницы
+const trashList = "trash"
+
+// TrashedTasks возвращает страницу задач из корзины, начиная с удалённых последними
+func (s *SQLStore) TrashedTasks(limit int, cursor string) (*TaskPage, error) {
+	c, err := decodeNewestCursor(cursor, trashList)
+	if err != nil {
+		return nil, err
+	}
+
+	where := conditions{"deleted_at != ''"}
+	var args []any
+	if c != nil {
+		cond, condArgs := c.olderCondition("deleted_at")
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+
+	// Лишняя задача показывает, есть ли следующая страница
+	query := `SELECT ` + taskColumns + ` FROM scheduler` + where.sql() + ` ORDER BY deleted_at DESC, id DESC LIMIT ?`
+	rows, err := s.query(query, append(args, limit+1)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tasks := make([]*Task, 0)
+	for rows.Next() {
+		task, err := scanTask(rows)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	page := trashPage(tasks, limit)
+	if err := s.loadTags(page.Tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadDeps(page.Tasks); err != nil {
+		return nil, err
+	}
+	if err := s.loadProgress(page.Tasks); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// trashPage обрезает выборку до limit задач и выдаёт курсор, если задач было больше
+func trashPage(tasks []*Task, limit int) *TaskPage {
+	page := &TaskPage{Tasks: tasks}
+	if len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		last := page.Tasks[limit-1]
+		page.NextCursor = newestCursor(trashList, last.DeletedAt, taskID(last))
+	}
+	return page
+}
+
+// RestoreTrashedTask возвращает задачу из корзины
+func (s *SQLStore) RestoreTrashedTask(id string) error {
+	query := `UPDATE scheduler SET deleted_at = '' WHERE id = ? AND deleted_at != ''`
+	res, err := s.exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("incorrect id for restoring task")
+	}
+
+	return nil
+}
+
+// PurgeTask удаляет задачу окончательно, в том числе из корзины
+func (s *SQLStore) PurgeTask(id string) error {
+	query := `DELETE FROM scheduler WHERE id = ?`
+	res, err := s.exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("incorrect id for purging task")
+	}
+
+	return nil
+}
+
+// PurgeTrashedTask окончательно удаляет задачу, только если она в корзине
+func (s *SQLStore) PurgeTrashedTask(id string) error {
+	query := `DELETE FROM scheduler WHERE id = ? AND deleted_at != ''`
+	res, err := s.exec(query, id)
+	if err != nil {
+		return err
+	}
+
+	count, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return fmt.Errorf("incorrect id for purging task")
+	}
+
+	return nil
+}
+
+// PurgeTrash окончательно удаляет задачи, попавшие в корзину раньше before.
+// Возвращает число удалённых задач.
+func (s *SQLStore) PurgeTrash(before time.Time) (int64, error) {
+	query := `DELETE FROM scheduler WHERE deleted_at != '' AND deleted_at < ?`
+	res, err := s.exec(query, before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// EmptyTrash окончательно удаляет все задачи из корзины
+func (s *SQLStore) EmptyTrash() (int64, error) {
+	query := `DELETE FROM scheduler WHERE deleted_at != ''`
+	res, err := s.exec(query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 
 	"github.com/Evrard-ro/final_project/pkg/api"
+	"github.com/Evrard-ro/final_project/pkg/db"
 )
 
 const (
@@ -31,14 +32,22 @@ func getWebDir() string {
 	return filepath.Join(wd, "web")
 }
 
-func Run() {
+func Run(store db.TaskStore) {
 	port := getPort()
 	webDir := getWebDir()
-	api.Init() // ← добавьте эту строку
+	if err := api.Init(); err != nil {
+		log.Fatalf("Ошибка настройки API: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	handlers := api.New(store)
+	handlers.Register(mux)
+	handlers.StartTrashPurger()
+
 	fs := http.FileServer(http.Dir(webDir))
-	http.Handle("/", fs)
+	mux.Handle("/", fs)
 	log.Printf("Сервер запущен на http://localhost:%s/", port)
-	err := http.ListenAndServe(":"+port, nil)
+	err := http.ListenAndServe(":"+port, mux)
 	if err != nil {
 		log.Fatalf("Ошибка запуска сервера: %v", err)
 	}
@@ -0,0 +1,108 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type bulkResp struct {
+	Applied bool `json:"applied"`
+	Results []struct {
+		Op    string `json:"op"`
+		ID    string `json:"id"`
+		Error string `json:"error"`
+	} `json:"results"`
+	Error string `json:"error"`
+}
+
+// bulk отправляет операции в /api/tasks/bulk
+func bulk(t *testing.T, atomic bool, operations ...map[string]any) bulkResp {
+	body, err := requestJSON("api/tasks/bulk", map[string]any{"atomic": atomic, "operations": operations}, http.MethodPost)
+	assert.NoError(t, err)
+
+	var resp bulkResp
+	assert.NoError(t, json.Unmarshal(body, &resp))
+	return resp
+}
+
+func TestBulk(t *testing.T) {
+	mark := fmt.Sprintf("bulk%d", time.Now().UnixNano())
+	day := func(n int) string {
+		return time.Now().AddDate(0, 0, n).Format(`20060102`)
+	}
+	search := "search=" + url.QueryEscape("comment:"+mark) + "&sort=date,title"
+
+	var ids []string
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+	for _, title := range []string{"Первая", "Вторая", "Третья"} {
+		ret, err := postJSON("api/task", map[string]any{"date": day(1), "title": title, "comment": mark}, http.MethodPost)
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	// Атомарный запрос с ошибкой не меняет ничего
+	resp := bulk(t, true,
+		map[string]any{"op": "delete", "id": ids[0]},
+		map[string]any{"op": "move", "id": ids[1], "date": "2024-01-01"},
+	)
+	assert.False(t, resp.Applied)
+	assert.NotEmpty(t, resp.Error)
+	if assert.Len(t, resp.Results, 2) {
+		assert.Empty(t, resp.Results[0].Error)
+		assert.NotEmpty(t, resp.Results[1].Error)
+	}
+	titles, errMsg := viewTasks(t, search)
+	assert.Empty(t, errMsg)
+	assert.Equal(t, []string{"Вторая", "Первая", "Третья"}, titles)
+
+	// Без atomic ошибочная операция пропускается, остальные применяются
+	resp = bulk(t, false,
+		map[string]any{"op": "add", "task": map[string]any{"date": day(2), "title": "Четвёртая", "comment": mark}},
+		map[string]any{"op": "update", "task": map[string]any{"id": ids[0], "date": day(3), "title": "Первая+", "comment": mark}},
+		map[string]any{"op": "move", "id": ids[1], "date": day(4)},
+		map[string]any{"op": "done", "id": ids[2]},
+		map[string]any{"op": "update", "task": map[string]any{"id": ids[0], "title": " "}},
+		map[string]any{"op": "archive", "id": ids[0]},
+	)
+	assert.True(t, resp.Applied)
+	if assert.Len(t, resp.Results, 6) {
+		for i, result := range resp.Results[:4] {
+			assert.Empty(t, result.Error, i)
+		}
+		assert.NotEmpty(t, resp.Results[0].ID)
+		ids = append(ids, resp.Results[0].ID)
+		assert.NotEmpty(t, resp.Results[4].Error)
+		assert.NotEmpty(t, resp.Results[5].Error)
+	}
+	titles, errMsg = viewTasks(t, search)
+	assert.Empty(t, errMsg)
+	assert.Equal(t, []string{"Четвёртая", "Первая+", "Вторая"}, titles)
+
+	// Перенос в прошлое без правила повторения ставит задачу на сегодня, как при изменении
+	resp = bulk(t, false, map[string]any{"op": "move", "id": ids[1], "date": day(-3)}, map[string]any{"op": "delete", "id": ids[0]})
+	assert.True(t, resp.Applied)
+	assert.Equal(t, day(0), getTaskJSON(t, ids[1])["date"])
+	titles, errMsg = viewTasks(t, search)
+	assert.Empty(t, errMsg)
+	assert.Equal(t, []string{"Вторая", "Четвёртая"}, titles)
+
+	// Ошибки самого запроса
+	for _, body := range []map[string]any{
+		{"operations": []map[string]any{}},
+		{"atomic": true},
+	} {
+		ret, err := postJSON("api/tasks/bulk", body, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"])
+	}
+}
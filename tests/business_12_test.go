@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getHolidays(t *testing.T, params string) []map[string]string {
+	body, err := requestJSON("api/holidays"+params, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string][]map[string]string
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m["holidays"]
+}
+
+func TestBusinessDays(t *testing.T) {
+	checkNextDate(t, []nextDate{
+		{"20240126", "bd", ""},
+		{"20240126", "bd 0", ""},
+		{"20240126", "bd 401", ""},
+		{"20240126", "m 24b", ""},
+		{"20240126", "m -24b", ""},
+		{"20240126", "m 0b", ""},
+		{"20240126", "m xb", ""},
+		{"20240126", "bd 1", "20240129"},
+		{"20240126", "bd 5", "20240202"},
+		{"20240101", "bd 10", "20240129"},
+		{"20240126", "m 1b", "20240201"},
+		{"20240126", "m 2b", "20240202"},
+		{"20240126", "m -1b", "20240131"},
+		{"20240126", "m -1b 3", "20240329"},
+		{"20240126", "m 15,-1b 2", "20240215"},
+	})
+
+	for _, v := range []map[string]any{
+		{"date": "", "title": "Пусто"},
+		{"date": "2024-01-29", "title": "Не тот формат"},
+	} {
+		m, err := postJSON("api/holidays", v, http.MethodPost)
+		assert.NoError(t, err)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0,
+			"Ожидается ошибка для праздника %v", v)
+	}
+
+	for _, date := range []string{"20240129", "20240131"} {
+		m, err := postJSON("api/holidays", map[string]any{
+			"date":  date,
+			"title": "Тестовый выходной",
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, m)
+	}
+	assert.Equal(t, 2, len(getHolidays(t, "?from=20240101&to=20240229")))
+
+	checkNextDate(t, []nextDate{
+		{"20240126", "bd 1", "20240130"},
+		{"20240126", "bd 3", "20240202"},
+		{"20240126", "m -1b", "20240130"},
+		{"20240126", "m -1b 1", "20240130"},
+	})
+
+	for _, date := range []string{"20240129", "20240131"} {
+		m, err := postJSON("api/holidays?date="+date, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, m)
+	}
+	assert.Empty(t, getHolidays(t, "?from=20240101&to=20240229"))
+
+	m, err := postJSON("api/holidays?date=20240129", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, m)
+}
@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getCompletions(t *testing.T, params string) []map[string]string {
+	completions, _ := getCompletionsPage(t, params)
+	return completions
+}
+
+// getCompletionsPage возвращает страницу истории выполнений и курсор следующей страницы
+func getCompletionsPage(t *testing.T, params string) ([]map[string]string, string) {
+	body, err := requestJSON("api/completions"+params, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m struct {
+		Completions []map[string]string `json:"completions"`
+		NextCursor  string              `json:"next_cursor"`
+	}
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+	return m.Completions, m.NextCursor
+}
+
+func TestCompletions(t *testing.T) {
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	body, err := requestJSON("api/completions?from=2024-01-01", nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	e, ok := m["error"]
+	assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для некорректной даты")
+
+	id := addTask(t, task{
+		date:  today,
+		title: "Разовая задача для истории",
+	})
+	ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	completions := getCompletions(t, fmt.Sprintf("?from=%s&to=%s&task_id=%s", today, today, id))
+	assert.Equal(t, 1, len(completions))
+	if len(completions) == 1 {
+		assert.Equal(t, id, completions[0]["task_id"])
+		assert.Equal(t, "Разовая задача для истории", completions[0]["title"])
+		assert.Equal(t, today, completions[0]["date"])
+		assert.NotEmpty(t, completions[0]["completed_at"])
+	}
+
+	id = addTask(t, task{
+		date:   today,
+		title:  "Повторяющаяся задача для истории",
+		repeat: "d 2",
+	})
+	for i := 0; i < 2; i++ {
+		ret, err := postJSON("api/task/done?id="+id, nil, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret)
+	}
+
+	completions = getCompletions(t, "?task_id="+id)
+	assert.Equal(t, 2, len(completions))
+	if len(completions) == 2 {
+		// Последние выполнения идут первыми
+		assert.Equal(t, now.AddDate(0, 0, 2).Format(`20060102`), completions[0]["date"])
+		assert.Equal(t, today, completions[1]["date"])
+	}
+
+	// История читается страницами: курсор продолжает выборку без повторов
+	page, cursor := getCompletionsPage(t, "?limit=1&task_id="+id)
+	if assert.Len(t, page, 1) && assert.NotEmpty(t, cursor) {
+		assert.Equal(t, completions[0]["id"], page[0]["id"])
+		page, cursor = getCompletionsPage(t, "?limit=1&task_id="+id+"&cursor="+cursor)
+		if assert.Len(t, page, 1) {
+			assert.Equal(t, completions[1]["id"], page[0]["id"])
+		}
+		assert.Empty(t, cursor)
+	}
+	for _, params := range []string{"?limit=0", "?limit=abc", "?cursor=abc"} {
+		body, err := requestJSON("api/completions"+params, nil, http.MethodGet)
+		assert.NoError(t, err)
+		var m map[string]any
+		assert.NoError(t, json.Unmarshal(body, &m))
+		assert.NotEmpty(t, m["error"], params)
+	}
+
+	tomorrow := now.AddDate(0, 0, 1).Format(`20060102`)
+	assert.Empty(t, getCompletions(t, fmt.Sprintf("?from=%s&task_id=%s", tomorrow, id)))
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
@@ -11,11 +11,20 @@ import (
 )
 
 type Task struct {
-	ID      int64  `db:"id"`
-	Date    string `db:"date"`
-	Title   string `db:"title"`
-	Comment string `db:"comment"`
-	Repeat  string `db:"repeat"`
+	ID        int64  `db:"id"`
+	Date      string `db:"date"`
+	Title     string `db:"title"`
+	Comment   string `db:"comment"`
+	Repeat    string `db:"repeat"`
+	Time      string `db:"time"`
+	Duration  int    `db:"duration"`
+	Priority  int    `db:"priority"`
+	ProjectID int64  `db:"project_id"`
+	Remaining int    `db:"remaining"`
+	Done      int    `db:"done"`
+	DeletedAt string `db:"deleted_at"`
+	CreatedAt string `db:"created_at"`
+	UpdatedAt string `db:"updated_at"`
 }
 
 func count(db *sqlx.DB) (int, error) {
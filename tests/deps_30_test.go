@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskDependencies(t *testing.T) {
+	mark := fmt.Sprintf("deps%d", time.Now().UnixNano())
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	var ids []string
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+	add := func(title string, deps ...string) string {
+		ret, err := postJSON("api/task", map[string]any{
+			"date": date, "title": title, "comment": mark, "depends_on": deps,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+		return fmt.Sprint(ret["id"])
+	}
+	paint := add("Купить краску")
+	fence := add("Покрасить забор", paint)
+	rest := add("Отдохнуть", fence)
+
+	task := getTaskJSON(t, fence)
+	assert.Equal(t, []any{paint}, task["depends_on"])
+	assert.Equal(t, []any{paint}, task["blocked_by"])
+	assert.Equal(t, []any{rest}, task["blocks"])
+
+	search := "search=" + url.QueryEscape("comment:"+mark)
+	for _, v := range []struct {
+		params string
+		want   []string
+	}{
+		{search + "&blocked=false", []string{"Купить краску"}},
+		{search + "&blocked=true", []string{"Покрасить забор", "Отдохнуть"}},
+	} {
+		titles, errMsg := viewTasks(t, v.params+"&sort=created")
+		assert.Empty(t, errMsg, v.params)
+		assert.Equal(t, v.want, titles, v.params)
+	}
+
+	// Заблокированную задачу нельзя выполнить без force=true
+	ret, err := postJSON("api/task/done?id="+fence, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+
+	// Цикл через другую задачу отклоняется, прежние зависимости сохраняются
+	ret, err = postJSON("api/task", map[string]any{
+		"id": paint, "date": date, "title": "Купить краску", "comment": mark, "depends_on": []string{rest},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret["error"])
+	assert.Nil(t, getTaskJSON(t, paint)["depends_on"])
+
+	// Изменение задачи без depends_on не трогает зависимости
+	ret, err = postJSON("api/task", map[string]any{"id": fence, "date": date, "title": "Покрасить забор", "comment": mark},
+		http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, []any{paint}, getTaskJSON(t, fence)["depends_on"])
+
+	// Выполнение блокера открывает следующую задачу
+	ret, err = postJSON("api/task/done?id="+paint, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Nil(t, getTaskJSON(t, fence)["blocked_by"])
+	titles, errMsg := viewTasks(t, search+"&blocked=false")
+	assert.Empty(t, errMsg)
+	assert.Equal(t, []string{"Покрасить забор"}, titles)
+
+	ret, err = postJSON("api/task/done?id="+fence+"&force=true", nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	// Ошибки
+	for _, v := range []map[string]any{
+		{"date": date, "title": "Задача", "depends_on": []string{"abc"}},
+		{"date": date, "title": "Задача", "depends_on": []string{"999999999"}},
+		{"id": rest, "date": date, "title": "Отдохнуть", "depends_on": []string{rest}},
+	} {
+		method := http.MethodPost
+		if v["id"] != nil {
+			method = http.MethodPut
+		}
+		ret, err := postJSON("api/task", v, method)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v)
+	}
+	_, errMsg = viewTasks(t, "blocked=maybe")
+	assert.NotEmpty(t, errMsg)
+}
+
+// Повторяющийся блокер не получает done, поэтому его снимает выполнение после появления связи
+func TestRepeatingBlocker(t *testing.T) {
+	mark := fmt.Sprintf("repdeps%d", time.Now().UnixNano())
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	var ids []string
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+	add := func(task map[string]any) string {
+		task["date"], task["comment"] = date, mark
+		ret, err := postJSON("api/task", task, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+		return fmt.Sprint(ret["id"])
+	}
+	water := add(map[string]any{"title": "Полить цветы", "repeat": "d 1"})
+	plant := add(map[string]any{"title": "Пересадить цветы", "depends_on": []string{water}})
+	assert.Equal(t, []any{water}, getTaskJSON(t, plant)["blocked_by"])
+
+	token := undoToken(t, "api/task/done?id="+water, http.MethodPost)
+	assert.NotEmpty(t, getTaskJSON(t, water)["id"])
+	assert.Equal(t, []any{water}, getTaskJSON(t, plant)["depends_on"])
+	assert.Nil(t, getTaskJSON(t, plant)["blocked_by"])
+	search := "search=" + url.QueryEscape("comment:"+mark)
+	titles, errMsg := viewTasks(t, search+"&blocked=true")
+	assert.Empty(t, errMsg)
+	assert.Empty(t, titles)
+
+	// Изменение задачи с прежним списком зависимостей не возвращает блокировку
+	ret, err := postJSON("api/task", map[string]any{
+		"id": plant, "date": date, "title": "Пересадить цветы", "comment": mark, "depends_on": []string{water},
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Nil(t, getTaskJSON(t, plant)["blocked_by"])
+
+	// Отмена выполнения удаляет запись о нём, и задача снова заблокирована
+	undo(t, token)
+	assert.Equal(t, []any{water}, getTaskJSON(t, plant)["blocked_by"])
+	titles, errMsg = viewTasks(t, search+"&blocked=true")
+	assert.Empty(t, errMsg)
+	assert.Equal(t, []string{"Пересадить цветы"}, titles)
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type taskItem struct {
+	ID       string `json:"id"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"`
+}
+
+// taskItems возвращает чек-лист задачи из /api/task/items
+func taskItems(t *testing.T, id string) []taskItem {
+	body, err := requestJSON("api/task/items?task_id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m struct {
+		Items []taskItem `json:"items"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m.Items
+}
+
+func TestTaskItems(t *testing.T) {
+	mark := fmt.Sprintf("items%d", time.Now().UnixNano())
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date": date, "title": "Уборка", "comment": mark, "repeat": "d 7",
+		"items": []map[string]any{{"title": " Пылесос "}, {"title": "Окна", "done": true}},
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	id := fmt.Sprint(ret["id"])
+	defer requestJSON("api/task?id="+id, nil, http.MethodDelete)
+
+	ret, err = postJSON("api/task/items", map[string]any{"task_id": id, "title": "Посуда"}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	items := taskItems(t, id)
+	if !assert.Len(t, items, 3) {
+		return
+	}
+	assert.Equal(t, "Пылесос", items[0].Title)
+	assert.True(t, items[1].Done)
+	assert.Equal(t, []int{1, 2, 3}, []int{items[0].Position, items[1].Position, items[2].Position})
+
+	// Перестановка пункта на первое место сдвигает остальные
+	ret, err = postJSON("api/task/items", map[string]any{"id": items[2].ID, "title": "Посуда", "done": true, "position": 1}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	items = taskItems(t, id)
+	assert.Equal(t, []string{"Посуда", "Пылесос", "Окна"}, []string{items[0].Title, items[1].Title, items[2].Title})
+
+	// Ход выполнения виден в списке задач и в самой задаче
+	progress := map[string]any{"done": float64(2), "total": float64(3)}
+	body, err := requestJSON("api/tasks?search="+url.QueryEscape("comment:"+mark), nil, http.MethodGet)
+	assert.NoError(t, err)
+	var list struct {
+		Tasks []map[string]any `json:"tasks"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &list))
+	if assert.Len(t, list.Tasks, 1) {
+		assert.Equal(t, progress, list.Tasks[0]["progress"])
+		assert.Nil(t, list.Tasks[0]["items"])
+	}
+	task := getTaskJSON(t, id)
+	assert.Equal(t, progress, task["progress"])
+	assert.Len(t, task["items"], 3)
+
+	// Выполнение повторяющейся задачи переносит её и снимает отметки
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, map[string]any{"done": float64(0), "total": float64(3)}, getTaskJSON(t, id)["progress"])
+
+	ret, err = postJSON("api/task/items?id="+items[0].ID, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Len(t, taskItems(t, id), 2)
+
+	// Ошибки
+	for _, v := range []struct {
+		path   string
+		body   map[string]any
+		method string
+	}{
+		{"api/task/items", map[string]any{"task_id": id, "title": " "}, http.MethodPost},
+		{"api/task/items", map[string]any{"title": "Пункт"}, http.MethodPost},
+		{"api/task/items", map[string]any{"task_id": "999999999", "title": "Пункт"}, http.MethodPost},
+		{"api/task/items", map[string]any{"id": items[0].ID, "title": "Пункт"}, http.MethodPut},
+		{"api/task/items?id=" + items[0].ID, nil, http.MethodDelete},
+		{"api/task", map[string]any{"date": date, "title": "Задача", "items": []map[string]any{{"title": ""}}}, http.MethodPost},
+	} {
+		ret, err := postJSON(v.path, v.body, v.method)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v.path)
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+
+	appdb "github.com/Evrard-ro/final_project/pkg/db"
+)
+
+// legacySchema — схема базы до появления миграций
+const legacySchema = `
+CREATE TABLE scheduler (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    date CHAR(8) NOT NULL DEFAULT '',
+    title VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    repeat VARCHAR(128) NOT NULL DEFAULT ''
+);
+CREATE INDEX idx_scheduler_date ON scheduler(date);
+`
+
+func TestSchemaVersion(t *testing.T) {
+	body, err := requestJSON("api/schema", nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m map[string]int
+	assert.NoError(t, json.Unmarshal(body, &m))
+	assert.Equal(t, appdb.LatestSchemaVersion(), m["version"])
+	assert.Equal(t, appdb.LatestSchemaVersion(), m["latest"])
+}
+
+func TestMigrate(t *testing.T) {
+	for name, script := range map[string]string{
+		"legacy": legacySchema,
+		// База, в которую часть столбцов уже добавили без учёта миграций
+		"partial": legacySchema + `ALTER TABLE scheduler ADD COLUMN time CHAR(5) NOT NULL DEFAULT '';`,
+	} {
+		dbfile := filepath.Join(t.TempDir(), name+".db")
+
+		legacy, err := sqlx.Connect("sqlite", dbfile)
+		assert.NoError(t, err)
+		_, err = legacy.Exec(script)
+		assert.NoError(t, err)
+		_, err = legacy.Exec(`INSERT INTO scheduler (date, title) VALUES ('20240126', 'Старая задача')`)
+		assert.NoError(t, err)
+		legacy.Close()
+
+		// Повторный запуск не должен ничего менять
+		for i := 0; i < 2; i++ {
+			store, err := appdb.OpenSQLite(dbfile)
+			if !assert.NoError(t, err, name) {
+				return
+			}
+			version, err := store.SchemaVersion()
+			assert.NoError(t, err)
+			assert.Equal(t, appdb.LatestSchemaVersion(), version, name)
+			assert.NoError(t, store.Close())
+		}
+
+		migrated, err := sqlx.Connect("sqlite", dbfile)
+		assert.NoError(t, err)
+
+		var task Task
+		err = migrated.Get(&task, `SELECT * FROM scheduler WHERE title = 'Старая задача'`)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "20240126", task.Date)
+		assert.Equal(t, "", task.Time)
+
+		var versions int
+		err = migrated.Get(&versions, `SELECT count(*) FROM schema_version`)
+		assert.NoError(t, err)
+		assert.Equal(t, appdb.LatestSchemaVersion(), versions)
+		migrated.Close()
+	}
+}
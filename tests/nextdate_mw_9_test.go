@@ -0,0 +1,23 @@
+package tests
+
+import "testing"
+
+func TestNextDateWeekdayPosition(t *testing.T) {
+	checkNextDate(t, []nextDate{
+		{"20240126", "mw", ""},
+		{"20240126", "mw 1", ""},
+		{"20240126", "mw 0:1", ""},
+		{"20240126", "mw 6:1", ""},
+		{"20240126", "mw -6:1", ""},
+		{"20240126", "mw 1:8", ""},
+		{"20240126", "mw 1:1 13", ""},
+		{"20240126", "mw 1:1", "20240205"},
+		{"20240126", "mw -1:5", "20240223"},
+		{"20240126", "mw 1:1,-1:5", "20240205"},
+		{"20240126", "mw 2:2 3,6", "20240312"},
+		{"20240126", "mw 5:4", "20240229"},
+		{"20240126", "mw -1:7 12", "20241229"},
+		{"20231106", "mw -1:1 2", "20240226"},
+		{"20240126", "mw 3:3 1", "20250115"},
+	})
+}
@@ -0,0 +1,29 @@
+package tests
+
+import "testing"
+
+func TestNextDateNegativeDays(t *testing.T) {
+	checkNextDate(t, []nextDate{
+		{"20240126", "m -32", ""},
+		{"20240126", "m -0", ""},
+		{"20240126", "m -1,-40", ""},
+		{"20240126", "m 30 2", ""},
+		{"20240126", "m -30,-31 2", ""},
+		{"20240126", "m -3", "20240129"},
+		{"20240126", "m -5,-3", "20240127"},
+		{"20240130", "m -3", "20240227"},
+		{"20240126", "m -31", "20240301"},
+		{"20240126", "m -30", "20240302"},
+		{"20240302", "m -30", "20240401"},
+		{"20240126", "m -29 2", "20240201"},
+		{"20240202", "m -29 2", "20280201"},
+		{"20240126", "m -28 2", "20240202"},
+		{"20240202", "m -28 2", "20250201"},
+		{"20240126", "m -31 4,6,9", ""},
+		{"20240126", "m -31,-1 4,6,9", "20240430"},
+		{"20240401", "m -31,15 4,5", "20240415"},
+		{"20240416", "m -31,15 4,5", "20240501"},
+		{"20240126", "m 1,-10 3", "20240301"},
+		{"20240302", "m 1,-10 3", "20240322"},
+	})
+}
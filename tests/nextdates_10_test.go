@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getNextDates(t *testing.T, date, repeat, params string) map[string]any {
+	urlPath := fmt.Sprintf("api/nextdates?now=20240126&date=%s&repeat=%s%s",
+		url.QueryEscape(date), url.QueryEscape(repeat), params)
+	body, err := getBody(urlPath)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func TestNextDates(t *testing.T) {
+	for _, v := range []struct {
+		repeat string
+		params string
+	}{
+		{"", ""},
+		{"ooops", ""},
+		{"d 7", "&count=abc"},
+		{"d 7", "&count=0"},
+		{"d 7", "&until=2024"},
+		{"RRULE:FREQ=DAILY;COUNT=3", ""},
+	} {
+		m := getNextDates(t, "20240101", v.repeat, v.params)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0,
+			"Ожидается ошибка для правила %q %q", v.repeat, v.params)
+	}
+
+	tbl := []struct {
+		repeat string
+		params string
+		want   []any
+	}{
+		{"d 7", "&count=3", []any{"20240129", "20240205", "20240212"}},
+		{"d 7", "&until=20240210", []any{"20240129", "20240205"}},
+		{"w 1,5", "&count=4", []any{"20240129", "20240202", "20240205", "20240209"}},
+		{"RRULE:FREQ=DAILY;COUNT=30", "", []any{"20240127", "20240128", "20240129", "20240130"}},
+		{"RRULE:FREQ=MONTHLY;BYDAY=-1FR", "&count=3", []any{"20240223", "20240329", "20240426"}},
+	}
+	for _, v := range tbl {
+		m := getNextDates(t, "20240101", v.repeat, v.params)
+		assert.Equal(t, v.want, m["dates"], "%q %q", v.repeat, v.params)
+	}
+
+	m := getNextDates(t, "20240101", "d 1", "&count=1000")
+	dates, ok := m["dates"].([]any)
+	assert.True(t, ok)
+	assert.Equal(t, 100, len(dates))
+}
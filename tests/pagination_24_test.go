@@ -0,0 +1,82 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tasksPage запрашивает страницу списка задач
+func tasksPage(t *testing.T, params string) (titles []string, next string, total *int, errMsg string) {
+	body, err := requestJSON("api/tasks?"+params, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m struct {
+		Tasks      []map[string]string `json:"tasks"`
+		NextCursor string              `json:"next_cursor"`
+		Total      *int                `json:"total"`
+		Error      string              `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	for _, task := range m.Tasks {
+		titles = append(titles, task["title"])
+	}
+	return titles, m.NextCursor, m.Total, m.Error
+}
+
+func TestTasksPagination(t *testing.T) {
+	if !Search {
+		return
+	}
+
+	mark := fmt.Sprintf("page%d", time.Now().UnixNano())
+	day := func(n int) string {
+		return time.Now().AddDate(0, 0, n).Format(`20060102`)
+	}
+	var ids []string
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+	// Две задачи на один день различаются только идентификатором
+	for i, offset := range []int{1, 2, 2, 3, 5} {
+		ids = append(ids, addTask(t, task{date: day(offset), title: fmt.Sprintf("Задача %d", i+1), comment: mark}))
+	}
+
+	search := "search=" + url.QueryEscape("comment:"+mark)
+	titles, next, total, errMsg := tasksPage(t, search+"&limit=2&total=true")
+	assert.Empty(t, errMsg)
+	assert.Equal(t, []string{"Задача 1", "Задача 2"}, titles)
+	assert.NotEmpty(t, next)
+	if assert.NotNil(t, total) {
+		assert.Equal(t, 5, *total)
+	}
+
+	// Задачи, добавленные во время просмотра, не сдвигают страницы:
+	// более ранняя не попадает в продолжение, более поздняя появляется в конце
+	ids = append(ids, addTask(t, task{date: day(1), title: "Задача раньше", comment: mark}))
+	ids = append(ids, addTask(t, task{date: day(4), title: "Задача позже", comment: mark}))
+
+	var rest []string
+	for next != "" {
+		titles, next, total, errMsg = tasksPage(t, search+"&limit=2&cursor="+next)
+		if !assert.Empty(t, errMsg) {
+			break
+		}
+		assert.Nil(t, total)
+		rest = append(rest, titles...)
+	}
+	assert.Equal(t, []string{"Задача 3", "Задача 4", "Задача позже", "Задача 5"}, rest)
+
+	for _, params := range []string{"limit=0", "limit=100000", "limit=два", "cursor=испорчен", search + "&cursor=e30"} {
+		titles, _, _, errMsg := tasksPage(t, params)
+		assert.Empty(t, titles, params)
+		assert.NotEmpty(t, errMsg, params)
+	}
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskPriority(t *testing.T) {
+	mark := fmt.Sprintf("prio%d", time.Now().UnixNano())
+	day := func(n int) string {
+		return time.Now().AddDate(0, 0, n).Format(`20060102`)
+	}
+
+	var ids []string
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+	for _, v := range []struct {
+		date     string
+		title    string
+		priority int
+	}{
+		{day(1), "Обычная", 0},
+		{day(1), "Срочная", 3},
+		{day(1), "Важная", 1},
+		{day(2), "Завтрашняя срочная", 3},
+	} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date": v.date, "title": v.title, "comment": mark, "priority": v.priority,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+	assert.Equal(t, float64(3), getTaskJSON(t, ids[1])["priority"])
+
+	// Приоритет можно изменить
+	ret, err := postJSON("api/task", map[string]any{
+		"id": ids[0], "date": day(1), "title": "Обычная", "comment": mark, "priority": 2,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+
+	// На одну дату важные задачи идут первыми
+	search := "search=" + url.QueryEscape("comment:"+mark)
+	for _, v := range []struct {
+		params string
+		want   []string
+	}{
+		{search, []string{"Срочная", "Обычная", "Важная", "Завтрашняя срочная"}},
+		{search + "&sort=-priority,title", []string{"Завтрашняя срочная", "Срочная", "Обычная", "Важная"}},
+		{search + "&priority=1,2", []string{"Обычная", "Важная"}},
+		{search + "&priority=3&limit=1", []string{"Срочная"}},
+		{"search=" + url.QueryEscape("comment:"+mark+" priority:<2"), []string{"Важная"}},
+	} {
+		titles, errMsg := viewTasks(t, v.params)
+		assert.Empty(t, errMsg, v.params)
+		assert.Equal(t, v.want, titles, v.params)
+	}
+
+	for _, priority := range []int{-1, 4} {
+		ret, err := postJSON("api/task", map[string]any{"date": day(1), "title": "Задача", "priority": priority}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], priority)
+	}
+	for _, params := range []string{"priority=4", "priority=срочно", "search=" + url.QueryEscape("priority:>5")} {
+		titles, errMsg := viewTasks(t, params)
+		assert.Empty(t, titles, params)
+		assert.NotEmpty(t, errMsg, params)
+	}
+}
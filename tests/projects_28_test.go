@@ -0,0 +1,120 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getProject возвращает проект из /api/projects?id=
+func getProject(t *testing.T, id string) map[string]any {
+	body, err := requestJSON("api/projects?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func TestProjects(t *testing.T) {
+	mark := fmt.Sprintf("proj%d", time.Now().UnixNano())
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	addProject := func(name, color string) string {
+		ret, err := postJSON("api/projects", map[string]any{"name": name, "color": color}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		return fmt.Sprint(ret["id"])
+	}
+	ops, release := addProject(" Ops "+mark, "#FF8800"), addProject("Release "+mark, "")
+
+	var ids []string
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+		requestJSON("api/projects?id="+release, nil, http.MethodDelete)
+	}()
+	for _, v := range []struct{ title, project string }{
+		{"Дежурство", ops}, {"Мониторинг", ops}, {"Заметки", ""},
+	} {
+		ret, err := postJSON("api/task", map[string]any{
+			"date": date, "title": v.title, "comment": mark, "project_id": v.project,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		assert.Empty(t, ret["error"])
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	project := getProject(t, ops)
+	assert.Equal(t, "Ops "+mark, project["name"])
+	assert.Equal(t, "#ff8800", project["color"])
+	assert.Equal(t, float64(2), project["tasks"])
+	assert.Equal(t, ops, getTaskJSON(t, ids[0])["project_id"])
+
+	search := "search=" + url.QueryEscape("comment:"+mark)
+	for _, v := range []struct {
+		params string
+		want   []string
+	}{
+		{"project=" + ops, []string{"Дежурство", "Мониторинг"}},
+		{search + "&project=none", []string{"Заметки"}},
+		{search + "&project=" + release, nil},
+	} {
+		titles, errMsg := viewTasks(t, v.params)
+		assert.Empty(t, errMsg, v.params)
+		assert.Equal(t, v.want, titles, v.params)
+	}
+
+	// Задачи архивного проекта пропадают из общего списка
+	ret, err := postJSON("api/projects", map[string]any{"id": ops, "name": "Ops " + mark, "archived": true}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	titles, _ := viewTasks(t, search)
+	assert.Equal(t, []string{"Заметки"}, titles)
+	body, err := requestJSON("api/projects", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(body), "Ops "+mark)
+	body, err = requestJSON("api/projects?archived=true", nil, http.MethodGet)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Ops "+mark)
+
+	// Удаление проекта переносит задачи в другой проект
+	ret, err = postJSON("api/projects?id="+ops+"&move_to="+release, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	titles, _ = viewTasks(t, search+"&project="+release)
+	assert.Equal(t, []string{"Дежурство", "Мониторинг"}, titles)
+	assert.Equal(t, release, getTaskJSON(t, ids[1])["project_id"])
+
+	// Ошибки
+	for _, v := range []struct {
+		path   string
+		body   map[string]any
+		method string
+	}{
+		{"api/projects", map[string]any{"name": " "}, http.MethodPost},
+		{"api/projects", map[string]any{"name": "Цвет", "color": "red"}, http.MethodPost},
+		{"api/projects", map[string]any{"id": ops, "name": "Нет"}, http.MethodPut},
+		{"api/projects?id=" + ops, nil, http.MethodDelete},
+		{"api/projects?id=" + release + "&move_to=" + ops, nil, http.MethodDelete},
+		{"api/projects?id=" + release + "&tasks=keep", nil, http.MethodDelete},
+		{"api/task", map[string]any{"date": date, "title": "Задача", "project_id": ops}, http.MethodPost},
+		{"api/tasks?project=ops", nil, http.MethodGet},
+	} {
+		ret, err := postJSON(v.path, v.body, v.method)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v.path)
+	}
+
+	// Удаление проекта с tasks=delete перемещает его задачи в корзину
+	ret, err = postJSON("api/projects?id="+release+"&tasks=delete", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	titles, _ = viewTasks(t, search)
+	assert.Equal(t, []string{"Заметки"}, titles)
+}
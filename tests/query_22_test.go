@@ -0,0 +1,62 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskQuery(t *testing.T) {
+	if !Search {
+		return
+	}
+
+	mark := fmt.Sprintf("query%d", time.Now().UnixNano())
+	other := fmt.Sprintf("meeting%d", time.Now().UnixNano())
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+	ids := []string{
+		addTask(t, task{date: day(1), title: "Квартальный отчёт " + mark, comment: "до пятницы", repeat: "m 1"}),
+		addTask(t, task{date: day(10), title: "Уборка " + mark, repeat: "w 6"}),
+		addTask(t, task{date: day(20), title: "Отчёт по проекту " + mark}),
+		addTask(t, task{date: day(30), title: "Re: meeting " + other, comment: "http://host/x в 10:30"}),
+	}
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+
+	for _, v := range []struct {
+		query string
+		want  []string
+	}{
+		{"title:отчёт title:" + mark, []string{"Квартальный отчёт " + mark, "Отчёт по проекту " + mark}},
+		{"title:отчёт has:comment " + mark, []string{"Квартальный отчёт " + mark}},
+		{"title:отчёт -has:comment " + mark, []string{"Отчёт по проекту " + mark}},
+		{mark + " repeat:w", []string{"Уборка " + mark}},
+		{mark + " -has:repeat", []string{"Отчёт по проекту " + mark}},
+		{mark + " after:" + day(5) + " before:" + day(15), []string{"Уборка " + mark}},
+		{mark + ` comment:"до пятницы"`, []string{"Квартальный отчёт " + mark}},
+		{mark + " -уборка -проекту", []string{"Квартальный отчёт " + mark}},
+		// Слово с неизвестным полем ищется как текст
+		{"re:meeting " + other, []string{"Re: meeting " + other}},
+		{other + " http://host/x 10:30", []string{"Re: meeting " + other}},
+	} {
+		titles, _, errMsg := searchTasks(t, v.query)
+		assert.Empty(t, errMsg, v.query)
+		assert.Equal(t, v.want, titles, v.query)
+	}
+
+	// Ошибки в запросе возвращаются в JSON
+	for _, query := range []string{"before:завтра", "repeat:hourly", "has:color", "title:"} {
+		titles, _, errMsg := searchTasks(t, query)
+		assert.Empty(t, titles, query)
+		assert.NotEmpty(t, errMsg, query)
+	}
+}
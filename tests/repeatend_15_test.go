@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepeatEnd(t *testing.T) {
+	checkNextDate(t, []nextDate{
+		{"20240120", "d 7 x0", ""},
+		{"20240120", "d 7 x", ""},
+		{"20240120", "d 7 x3 x4", ""},
+		{"20240120", "d 7 until 2024", ""},
+		{"20240120", "d 7 until 20240201 until 20240301", ""},
+		{"20240120", "d 7 until 20240201", "20240127"},
+		{"20240127", "d 7 until 20240201", ""},
+		{"20240120", "d 7 x3 until 20240201", "20240127"},
+		{"20240125", "w 1 x10", "20240129"},
+		{"20230311", "m 1 1,2 x3", "20240201"},
+		{"20240126", "mw -1:5 until 20240301", "20240223"},
+		{"20240102", "y until 20241231", ""},
+	})
+
+	m := getNextDates(t, "20240101", "d 7 x3", "")
+	assert.Equal(t, []any{"20240129", "20240205"}, m["dates"])
+	m = getNextDates(t, "20240101", "d 7 until 20240210", "")
+	assert.Equal(t, []any{"20240129", "20240205"}, m["dates"])
+
+	now := time.Now()
+
+	ret, err := postJSON("api/task", map[string]any{
+		"date":   now.Format(`20060102`),
+		"title":  "Окончание раньше начала",
+		"repeat": "d 1 until " + now.AddDate(0, 0, -1).Format(`20060102`),
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	e, ok := ret["error"]
+	assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для задачи с прошедшим окончанием")
+
+	// Ограничение числом повторений: после второго выполнения задача удаляется
+	id := addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "Две тренировки",
+		repeat: "d 1 x2",
+	})
+	assert.Equal(t, float64(2), getTaskJSON(t, id)["remaining"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	tsk := getTaskJSON(t, id)
+	assert.Equal(t, now.AddDate(0, 0, 1).Format(`20060102`), tsk["date"])
+	assert.Equal(t, float64(1), tsk["remaining"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+
+	// Ограничение датой: следующий повтор позже until завершает задачу
+	id = addTask(t, task{
+		date:   now.Format(`20060102`),
+		title:  "До конца недели",
+		repeat: "d 3 until " + now.AddDate(0, 0, 4).Format(`20060102`),
+	})
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, now.AddDate(0, 0, 3).Format(`20060102`), getTaskJSON(t, id)["date"])
+
+	ret, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+}
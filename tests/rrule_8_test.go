@@ -0,0 +1,60 @@
+package tests
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextDateRRule(t *testing.T) {
+	tbl := []nextDate{
+		{"20240126", "RRULE:", ""},
+		{"20240126", "RRULE:INTERVAL=2", ""},
+		{"20240126", "RRULE:FREQ=HOURLY", ""},
+		{"20240126", "RRULE:FREQ=DAILY;INTERVAL=0", ""},
+		{"20240126", "RRULE:FREQ=DAILY;BYDAY=1MO", ""},
+		{"20240126", "RRULE:FREQ=WEEKLY;BYMONTHDAY=1", ""},
+		{"20240126", "RRULE:FREQ=DAILY;COUNT=3;UNTIL=20241231", ""},
+		{"20240126", "RRULE:FREQ=MONTHLY;BYDAY=XX", ""},
+		{"20240126", "RRULE:FREQ=DAILY;BYSETPOS=1", ""},
+		{"20240113", "RRULE:FREQ=DAILY;INTERVAL=7", "20240127"},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=3", ""},
+		{"20240101", "RRULE:FREQ=DAILY;COUNT=40", "20240127"},
+		{"20240125", "RRULE:FREQ=WEEKLY;BYDAY=MO,TU,WE", "20240129"},
+		{"20240101", "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,FR", "20240129"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=2TU", "20240213"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", "20240223"},
+		{"20240227", "RRULE:FREQ=MONTHLY;BYDAY=-1FR", "20240329"},
+		{"20240115", "RRULE:FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=15;UNTIL=20241231", "20240415"},
+		{"20240115", "RRULE:FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=15;UNTIL=20240301", ""},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", "20240131"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1", "20240131"},
+		{"20240101", "RRULE:FREQ=MONTHLY;BYMONTHDAY=13;BYDAY=FR", "20240913"},
+		{"16890220", "RRULE:FREQ=YEARLY", "20240220"},
+		{"20200229", "RRULE:FREQ=YEARLY", "20240229"},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=4TH", "20241128"},
+		{"20240101", "RRULE:FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", ""},
+	}
+	checkNextDate(t, tbl)
+}
+
+// checkNextDate проверяет /api/nextdate для каждой строки таблицы при now=20240126
+func checkNextDate(t *testing.T, tbl []nextDate) {
+	for _, v := range tbl {
+		urlPath := fmt.Sprintf("api/nextdate?now=20240126&date=%s&repeat=%s",
+			url.QueryEscape(v.date), url.QueryEscape(v.repeat))
+		get, err := getBody(urlPath)
+		assert.NoError(t, err)
+		next := strings.TrimSpace(string(get))
+		_, err = time.Parse("20060102", next)
+		if err != nil && len(v.want) == 0 {
+			continue
+		}
+		assert.Equal(t, v.want, next, `{%q, %q, %q}`,
+			v.date, v.repeat, v.want)
+	}
+}
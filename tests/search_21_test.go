@@ -0,0 +1,103 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// searchTasks ищет задачи и возвращает их заголовки и фрагменты по порядку либо текст ошибки
+func searchTasks(t *testing.T, query string) (titles []string, snippets []string, errMsg string) {
+	body, err := requestJSON("api/tasks?search="+url.QueryEscape(query), nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m struct {
+		Tasks []map[string]string `json:"tasks"`
+		Error string              `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	for _, task := range m.Tasks {
+		titles = append(titles, task["title"])
+		snippets = append(snippets, task["snippet"])
+	}
+	return titles, snippets, m.Error
+}
+
+func TestFullTextSearch(t *testing.T) {
+	if !Search {
+		return
+	}
+
+	// Уникальное слово отделяет задачи теста от остальных
+	mark := fmt.Sprintf("fts%d", time.Now().UnixNano())
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+	ids := []string{
+		addTask(t, task{date: date, title: "Позвонить маме", comment: "Про молоко " + mark}),
+		addTask(t, task{date: date, title: "Купить молоко и хлеб", comment: mark}),
+		addTask(t, task{date: date, title: "Молочная ферма", comment: mark}),
+	}
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+
+	for _, v := range []struct {
+		query string
+		want  []string
+	}{
+		// Совпадение в заголовке важнее совпадения в комментарии
+		{mark + " молоко", []string{"Купить молоко и хлеб", "Позвонить маме"}},
+		{mark + " МОЛОКО", []string{"Купить молоко и хлеб", "Позвонить маме"}},
+		{mark + ` "молоко и хлеб"`, []string{"Купить молоко и хлеб"}},
+		{mark + ` "хлеб и молоко"`, nil},
+		{mark + " молоч*", []string{"Молочная ферма"}},
+		{mark + " AND (мама OR маме)", []string{"Позвонить маме"}},
+		{mark + " NOT молоко", []string{"Молочная ферма"}},
+		{mark + " NOT (молоко OR ферма)", nil},
+		// Последнее слово без кавычек ищется как начало слова, как прежний поиск по подстроке
+		{mark + " ферм", []string{"Молочная ферма"}},
+		{mark + " хле", []string{"Купить молоко и хлеб"}},
+		{mark + ` "ферм"`, nil},
+		{"ферм " + mark, nil},
+	} {
+		titles, _, errMsg := searchTasks(t, v.query)
+		assert.Empty(t, errMsg, v.query)
+		assert.Equal(t, v.want, titles, v.query)
+	}
+
+	_, snippets, _ := searchTasks(t, mark+" хлеб")
+	if assert.Len(t, snippets, 1) {
+		assert.Contains(t, snippets[0], "<mark>хлеб</mark>")
+	}
+
+	// Текст фрагмента экранируется, разметка из заголовка не попадает в HTML
+	ids = append(ids, addTask(t, task{date: date, title: "Сыр <img src=x onerror=alert(1)> & 5 > 3", comment: mark}))
+	_, snippets, _ = searchTasks(t, mark+" сыр")
+	if assert.Len(t, snippets, 1) {
+		assert.Contains(t, snippets[0], "<mark>Сыр</mark> &lt;img src=x onerror=alert(1)&gt; &amp; 5 &gt; 3")
+		assert.NotContains(t, snippets[0], "<img")
+	}
+
+	// Индекс следует за изменением задачи
+	_, err := postJSON("api/task", map[string]any{
+		"id":    ids[2],
+		"date":  date,
+		"title": "Сыроварня",
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	titles, _, _ := searchTasks(t, mark+" молоч*")
+	assert.Empty(t, titles)
+	titles, _, _ = searchTasks(t, "сыроварня")
+	assert.Equal(t, []string{"Сыроварня"}, titles)
+
+	for _, query := range []string{"NOT молоко", mark + " (молоко", "OR молоко", "***"} {
+		_, _, errMsg := searchTasks(t, query)
+		assert.NotEmpty(t, errMsg, query)
+	}
+}
@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTasksSort(t *testing.T) {
+	if !Search {
+		return
+	}
+
+	mark := fmt.Sprintf("sort%d", time.Now().UnixNano())
+	day := func(n int) string {
+		return time.Now().AddDate(0, 0, n).Format(`20060102`)
+	}
+	ids := []string{
+		addTask(t, task{date: day(2), title: "Бег", comment: mark}),
+		addTask(t, task{date: day(1), title: "Вода", comment: mark}),
+		addTask(t, task{date: day(2), title: "Арбуз", comment: mark}),
+		addTask(t, task{date: day(3), title: "Бег", comment: mark}),
+	}
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+
+	// Время создания и изменения возвращается вместе с задачей
+	task := getTaskJSON(t, ids[0])
+	assert.NotEmpty(t, task["created_at"])
+	assert.Equal(t, task["created_at"], task["updated_at"])
+
+	search := "search=" + url.QueryEscape("comment:"+mark)
+	for _, v := range []struct {
+		sort string
+		want []string
+	}{
+		{"", []string{"Вода", "Бег", "Арбуз", "Бег"}},
+		{"title", []string{"Арбуз", "Бег", "Бег", "Вода"}},
+		{"-title,-date", []string{"Вода", "Бег", "Бег", "Арбуз"}},
+		{"-date,title", []string{"Бег", "Арбуз", "Бег", "Вода"}},
+		{"created", []string{"Бег", "Вода", "Арбуз", "Бег"}},
+	} {
+		titles, errMsg := viewTasks(t, search+"&sort="+url.QueryEscape(v.sort))
+		assert.Empty(t, errMsg, v.sort)
+		assert.Equal(t, v.want, titles, v.sort)
+
+		// Постранично задачи идут в том же порядке
+		var paged []string
+		for next, params := "", search+"&limit=1&sort="+url.QueryEscape(v.sort); ; {
+			titles, cursor, _, errMsg := tasksPage(t, params+"&cursor="+next)
+			if !assert.Empty(t, errMsg, v.sort) {
+				break
+			}
+			paged = append(paged, titles...)
+			if next = cursor; next == "" {
+				break
+			}
+		}
+		assert.Equal(t, v.want, paged, v.sort)
+	}
+
+	for _, sort := range []string{"importance", "title,title", "date desc", "id"} {
+		titles, errMsg := viewTasks(t, "sort="+url.QueryEscape(sort))
+		assert.Empty(t, titles, sort)
+		assert.NotEmpty(t, errMsg, sort)
+	}
+}
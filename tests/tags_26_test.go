@@ -0,0 +1,119 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tagCounts возвращает число задач с каждым тегом из /api/tags
+func tagCounts(t *testing.T) map[string]int {
+	body, err := requestJSON("api/tags", nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m struct {
+		Tags []struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		} `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	counts := make(map[string]int)
+	for _, tag := range m.Tags {
+		counts[tag.Name] = tag.Count
+	}
+	return counts
+}
+
+func TestTags(t *testing.T) {
+	mark := fmt.Sprintf("tag%d", time.Now().UnixNano())
+	work, home := mark+"-work", mark+"-дом"
+	date := time.Now().AddDate(0, 0, 1).Format(`20060102`)
+
+	var ids []string
+	defer func() {
+		for _, id := range ids {
+			requestJSON("api/task?id="+id, nil, http.MethodDelete)
+		}
+	}()
+	for _, v := range []struct {
+		title string
+		tags  []string
+	}{
+		{"Отчёт", []string{"#" + work, " " + work + " ", home}},
+		{"Уборка", []string{home}},
+	} {
+		ret, err := postJSON("api/task", map[string]any{"date": date, "title": v.title, "tags": v.tags}, http.MethodPost)
+		assert.NoError(t, err)
+		ids = append(ids, fmt.Sprint(ret["id"]))
+	}
+
+	// Теги приводятся к одному виду и возвращаются по алфавиту без повторов
+	task := getTaskJSON(t, ids[0])
+	assert.Equal(t, []any{work, home}, task["tags"])
+
+	// Изменение без поля tags оставляет теги прежними, пустой список их снимает
+	ret, err := postJSON("api/task", map[string]any{"id": ids[1], "date": date, "title": "Уборка дома"}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, []any{home}, getTaskJSON(t, ids[1])["tags"])
+
+	counts := tagCounts(t)
+	assert.Equal(t, 1, counts[work])
+	assert.Equal(t, 2, counts[home])
+
+	for _, v := range []struct {
+		params string
+		want   []string
+	}{
+		{"tag=" + url.QueryEscape(home), []string{"Отчёт", "Уборка дома"}},
+		{"tag=" + url.QueryEscape(home) + "&tag=" + url.QueryEscape(work), []string{"Отчёт"}},
+		{"search=" + url.QueryEscape("tag:"+home+" -tag:"+work), []string{"Уборка дома"}},
+	} {
+		titles, errMsg := viewTasks(t, v.params)
+		assert.Empty(t, errMsg, v.params)
+		assert.Equal(t, v.want, titles, v.params)
+	}
+
+	// Переименование и объединение
+	renamed := mark + "-работа"
+	ret, err = postJSON("api/tags", map[string]any{"name": work, "new_name": renamed}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Equal(t, []any{home, renamed}, getTaskJSON(t, ids[0])["tags"])
+
+	ret, err = postJSON("api/tags/merge", map[string]any{"tags": []string{home, renamed}, "into": work}, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	counts = tagCounts(t)
+	assert.Equal(t, 2, counts[work])
+	assert.NotContains(t, counts, home)
+	assert.NotContains(t, counts, renamed)
+
+	ret, err = postJSON("api/task", map[string]any{"id": ids[0], "date": date, "title": "Отчёт", "tags": []string{}}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, ret["error"])
+	assert.Nil(t, getTaskJSON(t, ids[0])["tags"])
+
+	// Ошибки
+	for _, v := range []struct {
+		path   string
+		body   map[string]any
+		method string
+	}{
+		{"api/task", map[string]any{"date": date, "title": "Задача", "tags": []string{"два слова"}}, http.MethodPost},
+		{"api/task", map[string]any{"date": date, "title": "Задача", "tags": []string{"#"}}, http.MethodPost},
+		{"api/tags", map[string]any{"name": home, "new_name": "новый"}, http.MethodPut},
+		{"api/tags", map[string]any{"name": work, "new_name": ""}, http.MethodPut},
+		{"api/tags/merge", map[string]any{"into": work}, http.MethodPost},
+	} {
+		ret, err := postJSON(v.path, v.body, v.method)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], v.body)
+	}
+}
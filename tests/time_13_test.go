@@ -0,0 +1,86 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getTaskJSON(t *testing.T, id string) map[string]any {
+	body, err := requestJSON("api/task?id="+id, nil, http.MethodGet)
+	assert.NoError(t, err)
+	var m map[string]any
+	assert.NoError(t, json.Unmarshal(body, &m))
+	return m
+}
+
+func TestTaskTime(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	now := time.Now().Format(`20060102`)
+
+	for _, v := range []map[string]any{
+		{"title": "Созвон", "time": "25:00"},
+		{"title": "Созвон", "time": "9:30"},
+		{"title": "Созвон", "time": "1430"},
+		{"title": "Созвон", "time": "14:30", "duration": -5},
+		{"title": "Созвон", "time": "14:30", "duration": 2000},
+		{"title": "Созвон", "duration": 30},
+	} {
+		v["date"] = now
+		m, err := postJSON("api/task", v, http.MethodPost)
+		assert.NoError(t, err)
+		e, ok := m["error"]
+		assert.False(t, !ok || len(fmt.Sprint(e)) == 0,
+			"Ожидается ошибка для задачи %v", v)
+	}
+
+	m, err := postJSON("api/task", map[string]any{
+		"date":     now,
+		"title":    "Созвон с командой",
+		"repeat":   "d 7",
+		"time":     "14:30",
+		"duration": 45,
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	id := fmt.Sprint(m["id"])
+
+	task := getTaskJSON(t, id)
+	assert.Equal(t, "14:30", task["time"])
+	assert.Equal(t, float64(45), task["duration"])
+
+	var dbTask Task
+	err = db.Get(&dbTask, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, "14:30", dbTask.Time)
+	assert.Equal(t, 45, dbTask.Duration)
+
+	m, err = postJSON("api/task", map[string]any{
+		"id":       id,
+		"date":     now,
+		"title":    "Созвон с командой",
+		"repeat":   "d 7",
+		"time":     "09:15",
+		"duration": 30,
+	}, http.MethodPut)
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+
+	m, err = postJSON("api/task/done?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+
+	task = getTaskJSON(t, id)
+	assert.Equal(t, time.Now().AddDate(0, 0, 7).Format(`20060102`), task["date"])
+	assert.Equal(t, "09:15", task["time"])
+	assert.Equal(t, float64(30), task["duration"])
+
+	m, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, m)
+}
@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimezone(t *testing.T) {
+	m, err := postJSON("api/task?tz=Mars/Olympus", map[string]any{
+		"title": "Неизвестный пояс",
+	}, http.MethodPost)
+	assert.NoError(t, err)
+	e, ok := m["error"]
+	assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для неизвестного пояса")
+
+	// Пояса UTC+14 и UTC-11 почти всегда дают разные «сегодня»
+	for _, zone := range []string{"Pacific/Kiritimati", "Pacific/Pago_Pago"} {
+		loc, err := time.LoadLocation(zone)
+		if err != nil {
+			t.Skipf("нет данных о часовом поясе %s: %v", zone, err)
+		}
+		today := time.Now().In(loc)
+
+		m, err := postJSON("api/task?tz="+url.QueryEscape(zone), map[string]any{
+			"title": "Задача в поясе " + zone,
+		}, http.MethodPost)
+		assert.NoError(t, err)
+		id := fmt.Sprint(m["id"])
+
+		task := getTaskJSON(t, id)
+		assert.Equal(t, today.Format(`20060102`), task["date"], zone)
+
+		m, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.Empty(t, m)
+
+		body, err := getBody(fmt.Sprintf("api/nextdate?tz=%s&date=%s&repeat=%s",
+			url.QueryEscape(zone), today.Format(`20060102`), url.QueryEscape("d 1")))
+		assert.NoError(t, err)
+		assert.Equal(t, today.AddDate(0, 0, 1).Format(`20060102`), strings.TrimSpace(string(body)), zone)
+	}
+}
@@ -0,0 +1,140 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trashPage возвращает идентификаторы задач одной страницы корзины и курсор следующей
+func trashPage(t *testing.T, params string) ([]string, string) {
+	body, err := requestJSON("api/trash"+params, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m struct {
+		Tasks      []map[string]any `json:"tasks"`
+		NextCursor string           `json:"next_cursor"`
+	}
+	err = json.Unmarshal(body, &m)
+	assert.NoError(t, err)
+
+	var ids []string
+	for _, task := range m.Tasks {
+		ids = append(ids, fmt.Sprint(task["id"]))
+		assert.NotEmpty(t, task["deleted_at"])
+	}
+	return ids, m.NextCursor
+}
+
+// trashIDs возвращает идентификаторы всех задач корзины, проходя по страницам
+func trashIDs(t *testing.T) map[string]bool {
+	ids := make(map[string]bool)
+	page, cursor := trashPage(t, "")
+	for {
+		for _, id := range page {
+			ids[id] = true
+		}
+		if cursor == "" {
+			return ids
+		}
+		page, cursor = trashPage(t, "?cursor="+cursor)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	id := addTask(t, task{
+		date:  time.Now().Format(`20060102`),
+		title: "Задача для корзины",
+	})
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	notFoundTask(t, id)
+	assert.True(t, trashIDs(t)[id])
+
+	// Задача в корзине остаётся в базе
+	var stored Task
+	err = db.Get(&stored, `SELECT * FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, stored.DeletedAt)
+
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Equal(t, "Задача для корзины", getTaskJSON(t, id)["title"])
+	assert.False(t, trashIDs(t)[id])
+
+	// Восстановить можно только задачу из корзины
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret)
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret)
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	ret, err = postJSON("api/trash?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.False(t, trashIDs(t)[id])
+
+	var count int
+	err = db.Get(&count, `SELECT count(id) FROM scheduler WHERE id=?`, id)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	ret, err = postJSON("api/trash/restore?id="+id, nil, http.MethodPost)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, ret)
+
+	// Очистка корзины целиком
+	id = addTask(t, task{
+		date:  time.Now().Format(`20060102`),
+		title: "Ещё одна задача для корзины",
+	})
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	// Без id и all=true ничего не удаляется
+	for _, path := range []string{"api/trash", "api/trash?id=", "api/trash?all=true&id=" + id} {
+		ret, err = postJSON(path, nil, http.MethodDelete)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], path)
+		assert.True(t, trashIDs(t)[id], path)
+	}
+
+	// Корзина читается страницами, начиная с удалённых последними
+	last := addTask(t, task{
+		date:  time.Now().Format(`20060102`),
+		title: "Последняя задача для корзины",
+	})
+	ret, err = postJSON("api/task?id="+last, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	page, cursor := trashPage(t, "?limit=1")
+	assert.Equal(t, []string{last}, page)
+	if assert.NotEmpty(t, cursor) {
+		page, _ = trashPage(t, "?limit=1&cursor="+cursor)
+		assert.Equal(t, []string{id}, page)
+	}
+	for _, params := range []string{"?limit=0", "?limit=abc", "?cursor=abc"} {
+		ret, err = postJSON("api/trash"+params, nil, http.MethodGet)
+		assert.NoError(t, err)
+		assert.NotEmpty(t, ret["error"], params)
+	}
+
+	ret, err = postJSON("api/trash?all=true", nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+	assert.Empty(t, trashIDs(t))
+}
@@ -0,0 +1,105 @@
+package tests
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// undoToken выполняет запрос и возвращает токен отмены из заголовка ответа
+func undoToken(t *testing.T, apipath string, method string) string {
+	req, err := http.NewRequest(method, getURL(apipath), nil)
+	assert.NoError(t, err)
+
+	client := &http.Client{}
+	if len(Token) > 0 {
+		jar, err := cookiejar.New(nil)
+		assert.NoError(t, err)
+		jar.SetCookies(req.URL, []*http.Cookie{
+			{
+				Name:  "token",
+				Value: Token,
+			},
+		})
+		client.Jar = jar
+	}
+
+	resp, err := client.Do(req)
+	assert.NoError(t, err)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	token := resp.Header.Get("X-Undo-Token")
+	assert.NotEmpty(t, token)
+	return token
+}
+
+func undo(t *testing.T, token string) map[string]any {
+	ret, err := postJSON("api/undo?token="+token, nil, http.MethodPost)
+	assert.NoError(t, err)
+	return ret
+}
+
+func TestUndo(t *testing.T) {
+	now := time.Now()
+	today := now.Format(`20060102`)
+
+	ret := undo(t, "unknown")
+	e, ok := ret["error"]
+	assert.False(t, !ok || len(fmt.Sprint(e)) == 0, "Ожидается ошибка для неизвестного токена")
+
+	// Отмена удаления возвращает задачу с прежним идентификатором
+	id := addTask(t, task{
+		date:    today,
+		title:   "Удалить и вернуть",
+		comment: "Комментарий",
+		repeat:  "d 5",
+	})
+	token := undoToken(t, "api/task?id="+id, http.MethodDelete)
+	notFoundTask(t, id)
+	assert.Empty(t, undo(t, token))
+
+	tsk := getTaskJSON(t, id)
+	assert.Equal(t, "Удалить и вернуть", tsk["title"])
+	assert.Equal(t, "Комментарий", tsk["comment"])
+	assert.Equal(t, "d 5", tsk["repeat"])
+	assert.Equal(t, today, tsk["date"])
+
+	// Повторно тот же токен не действует
+	_, ok = undo(t, token)["error"]
+	assert.True(t, ok)
+
+	// Отмена выполнения повторяющейся задачи возвращает прежнюю дату и убирает запись из истории
+	token = undoToken(t, "api/task/done?id="+id, http.MethodPost)
+	assert.Equal(t, now.AddDate(0, 0, 5).Format(`20060102`), getTaskJSON(t, id)["date"])
+	assert.Equal(t, 1, len(getCompletions(t, "?task_id="+id)))
+	assert.Empty(t, undo(t, token))
+	assert.Equal(t, today, getTaskJSON(t, id)["date"])
+	assert.Empty(t, getCompletions(t, "?task_id="+id))
+
+	ret, err := postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+
+	// Отмена выполнения разовой задачи возвращает удалённую задачу
+	id = addTask(t, task{
+		date:  today,
+		title: "Выполнить и вернуть",
+	})
+	token = undoToken(t, "api/task/done?id="+id, http.MethodPost)
+	notFoundTask(t, id)
+	assert.Empty(t, undo(t, token))
+	assert.Equal(t, "Выполнить и вернуть", getTaskJSON(t, id)["title"])
+	assert.Empty(t, getCompletions(t, "?task_id="+id))
+
+	ret, err = postJSON("api/task?id="+id, nil, http.MethodDelete)
+	assert.NoError(t, err)
+	assert.Empty(t, ret)
+}
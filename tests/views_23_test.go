@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// viewTasks запрашивает список задач с параметрами и возвращает заголовки или текст ошибки
+func viewTasks(t *testing.T, params string) ([]string, string) {
+	body, err := requestJSON("api/tasks?"+params, nil, http.MethodGet)
+	assert.NoError(t, err)
+
+	var m struct {
+		Tasks []map[string]any `json:"tasks"`
+		Error string           `json:"error"`
+	}
+	assert.NoError(t, json.Unmarshal(body, &m))
+	var titles []string
+	for _, task := range m.Tasks {
+		titles = append(titles, fmt.Sprint(task["title"]))
+	}
+	return titles, m.Error
+}
+
+func TestTaskViews(t *testing.T) {
+	db := openDB(t)
+	defer db.Close()
+
+	mark := fmt.Sprintf("view%d", time.Now().UnixNano())
+	now := time.Now()
+	day := func(n int) string {
+		return now.AddDate(0, 0, n).Format(`20060102`)
+	}
+
+	// Задачи с прошедшей датой через API не создать, поэтому пишем их прямо в базу
+	offsets := map[string]int{"past": -3, "repeat": -10, "today": 0, "soon": 3, "later": 20}
+	for title, offset := range offsets {
+		repeat := ""
+		if title == "repeat" {
+			repeat = "d 7"
+		}
+		_, err := db.Exec(`INSERT INTO scheduler (date, title, comment, repeat) VALUES (?, ?, ?, ?)`,
+			day(offset), title+" "+mark, "", repeat)
+		assert.NoError(t, err)
+	}
+	defer db.Exec(`DELETE FROM scheduler WHERE title LIKE ?`, "% "+mark)
+
+	// Неделя с понедельника по воскресенье
+	monday := now.AddDate(0, 0, -(int(now.Weekday())+6)%7).Format(`20060102`)
+	sunday := now.AddDate(0, 0, 6-(int(now.Weekday())+6)%7).Format(`20060102`)
+	var week []string
+	for _, title := range []string{"repeat", "past", "today", "soon", "later"} {
+		if d := day(offsets[title]); d >= monday && d <= sunday {
+			week = append(week, title+" "+mark)
+		}
+	}
+
+	for _, v := range []struct {
+		params string
+		want   []string
+	}{
+		{"view=overdue", []string{"repeat " + mark, "past " + mark}},
+		{"view=today", []string{"today " + mark}},
+		{"view=next7", []string{"today " + mark, "soon " + mark}},
+		{"view=week", week},
+		{"from=" + day(1) + "&to=" + day(30), []string{"soon " + mark, "later " + mark}},
+		{"from=" + now.AddDate(0, 0, 4).Format(`02.01.2006`), []string{"later " + mark}},
+		{"to=" + day(-5), []string{"repeat " + mark}},
+	} {
+		titles, errMsg := viewTasks(t, v.params+"&search="+mark)
+		assert.Empty(t, errMsg, v.params)
+		assert.Equal(t, v.want, titles, v.params)
+	}
+
+	for _, params := range []string{"view=month", "view=today&from=" + day(0), "from=вчера", "to=20261340"} {
+		titles, errMsg := viewTasks(t, params)
+		assert.Empty(t, titles, params)
+		assert.NotEmpty(t, errMsg, params)
+	}
+}